@@ -0,0 +1,28 @@
+package configmap
+
+import (
+	"github.com/rancher/norman/types"
+	"github.com/rancher/types/apis/project.cattle.io/v3/schema"
+	"github.com/rancher/types/client/project/v3"
+	"github.com/rancher/types/factory"
+	"k8s.io/api/core/v1"
+)
+
+type configMapOverride struct {
+	types.Namespaced
+	ProjectID  string            `norman:"type=reference[/v3/schemas/project],noupdate"`
+	BinaryData map[string][]byte `json:"binaryData"`
+}
+
+// ConfigureSchema replaces the configMap schema from rancher/types, which is not
+// namespaced, with one that has a namespaceId, a projectId and binaryData.
+func ConfigureSchema(schemas *types.Schemas) *types.Schema {
+	configMapSchema := schemas.Schema(&schema.Version, client.ConfigMapType)
+
+	namespaced := factory.Schemas(&schema.Version).
+		MustImport(&schema.Version, v1.ConfigMap{}, configMapOverride{}).
+		Schema(&schema.Version, client.ConfigMapType)
+
+	*configMapSchema = *namespaced
+	return configMapSchema
+}
@@ -0,0 +1,61 @@
+package configmap
+
+import (
+	"encoding/base64"
+
+	"github.com/rancher/cluster-api/store/selector"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	dataField       = "data"
+	binaryDataField = "binaryData"
+)
+
+type Store struct {
+	types.Store
+}
+
+func NewConfigMapStore(k8sClient rest.Interface) *Store {
+	return &Store{
+		Store: selector.NewProxyStore(k8sClient,
+			[]string{"api"},
+			"",
+			"v1",
+			"ConfigMap",
+			"configmaps",
+			nil),
+	}
+}
+
+func (s *Store) Create(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}) (map[string]interface{}, error) {
+	if err := validateData(data); err != nil {
+		return nil, err
+	}
+	return s.Store.Create(apiContext, schema, data)
+}
+
+func (s *Store) Update(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}, id string) (map[string]interface{}, error) {
+	if err := validateData(data); err != nil {
+		return nil, err
+	}
+	return s.Store.Update(apiContext, schema, data, id)
+}
+
+// validateData ensures binaryData only holds base64 values and that no key is
+// set in both data and binaryData, which the apiserver would reject.
+func validateData(data map[string]interface{}) error {
+	textData := convert.ToMapInterface(data[dataField])
+	for key, value := range convert.ToMapInterface(data[binaryDataField]) {
+		if _, ok := textData[key]; ok {
+			return httperror.NewFieldAPIError(httperror.NotUnique, binaryDataField, "key "+key+" is also set in data")
+		}
+		if _, err := base64.StdEncoding.DecodeString(convert.ToString(value)); err != nil {
+			return httperror.NewFieldAPIError(httperror.InvalidFormat, binaryDataField, "value of key "+key+" is not base64 encoded")
+		}
+	}
+	return nil
+}
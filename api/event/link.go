@@ -0,0 +1,143 @@
+package event
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/rancher/cluster-api/auth"
+	"github.com/rancher/norman/api/access"
+	"github.com/rancher/norman/api/handler"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/types/client/project/v3"
+)
+
+const EventsLink = "events"
+
+// kinds maps the types that have events, lower cased like the type prefix of
+// aggregated workload IDs, to the kind their events are recorded for
+var kinds = map[string]string{
+	"cronjob":               "CronJob",
+	"daemonset":             "DaemonSet",
+	"deployment":            "Deployment",
+	"ingress":               "Ingress",
+	"job":                   "Job",
+	"node":                  "Node",
+	"persistentvolumeclaim": "PersistentVolumeClaim",
+	"pod":                   "Pod",
+	"replicaset":            "ReplicaSet",
+	"replicationcontroller": "ReplicationController",
+	"statefulset":           "StatefulSet",
+}
+
+// AddLink adds the events link to the schemas. Their own formatters and list
+// handlers still run, the events link is handled before them.
+func AddLink(schemas ...*types.Schema) {
+	for _, linkSchema := range schemas {
+		if linkSchema == nil {
+			continue
+		}
+
+		formatter := linkSchema.Formatter
+		linkSchema.Formatter = func(apiContext *types.APIContext, resource *types.RawResource) {
+			if formatter != nil {
+				formatter(apiContext, resource)
+			}
+			Formatter(apiContext, resource)
+		}
+
+		listHandler := linkSchema.ListHandler
+		linkSchema.ListHandler = func(apiContext *types.APIContext) error {
+			if apiContext.Link == EventsLink {
+				return ListEvents(apiContext)
+			}
+			if listHandler != nil {
+				return listHandler(apiContext)
+			}
+			return handler.ListHandler(apiContext)
+		}
+	}
+}
+
+// Formatter adds the events link when the caller may list the events of the resource
+func Formatter(apiContext *types.APIContext, resource *types.RawResource) {
+	if apiContext.Schemas.Schema(apiContext.Version, EventType) == nil {
+		return
+	}
+
+	namespace, _ := resource.Values[client.PodFieldNamespaceId].(string)
+	if auth.Can(apiContext, auth.Permission{
+		Verb:      "list",
+		Resource:  "events",
+		Namespace: namespace,
+	}) {
+		resource.Links[EventsLink] = apiContext.URLBuilder.Link(EventsLink, resource)
+	}
+}
+
+// ListEvents serves the events link with the events of the resource, oldest
+// first. Clients follow new ones by subscribing to the event type with the
+// same involvedObjectKind and involvedObjectName filters.
+func ListEvents(apiContext *types.APIContext) error {
+	if err := access.ByID(apiContext, apiContext.Version, apiContext.Type, apiContext.ID, nil); err != nil {
+		return err
+	}
+
+	eventSchema := apiContext.Schemas.Schema(apiContext.Version, EventType)
+	kind, namespace, name := involvedObject(apiContext.Schema, apiContext.ID)
+	if eventSchema == nil || kind == "" {
+		return httperror.NewAPIError(httperror.NotFound, "Link not found")
+	}
+
+	conditions := []*types.QueryCondition{
+		types.NewConditionFromString(InvolvedObjectKindField, types.ModifierEQ, kind),
+		types.NewConditionFromString(InvolvedObjectNameField, types.ModifierEQ, name),
+	}
+	if namespace != "" {
+		conditions = append(conditions, types.NewConditionFromString(client.PodFieldNamespaceId, types.ModifierEQ, namespace))
+	}
+
+	eventContext := *apiContext
+	eventContext.Type = eventSchema.ID
+	eventContext.Schema = eventSchema
+
+	data, err := eventSchema.Store.List(&eventContext, eventSchema, &types.QueryOptions{
+		Conditions: conditions,
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(data, func(i, j int) bool {
+		return lastSeen(data[i]) < lastSeen(data[j])
+	})
+
+	eventContext.WriteResponse(http.StatusOK, data)
+	return nil
+}
+
+// involvedObject resolves an ID to the object events are recorded for. IDs of
+// aggregated workloads start with the workload type, like deployment:ns:name,
+// the others are namespace:name or just the name of cluster resources.
+func involvedObject(schema *types.Schema, id string) (string, string, string) {
+	parts := strings.SplitN(id, ":", 3)
+	if len(parts) == 3 {
+		return kinds[parts[0]], parts[1], parts[2]
+	}
+
+	kind := kinds[strings.ToLower(schema.ID)]
+	if len(parts) == 2 {
+		return kind, parts[0], parts[1]
+	}
+	return kind, "", parts[0]
+}
+
+// lastSeen is when an event last happened, newer events only have an eventTime
+func lastSeen(event map[string]interface{}) string {
+	if last := convert.ToString(event["lastTimestamp"]); last != "" {
+		return last
+	}
+	return convert.ToString(event["eventTime"])
+}
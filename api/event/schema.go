@@ -0,0 +1,104 @@
+package event
+
+import (
+	"net/http"
+
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	m "github.com/rancher/norman/types/mapper"
+	"github.com/rancher/norman/types/values"
+	clusterSchema "github.com/rancher/types/apis/cluster.cattle.io/v3/schema"
+	"github.com/rancher/types/apis/project.cattle.io/v3/schema"
+	"github.com/rancher/types/factory"
+	"k8s.io/api/core/v1"
+)
+
+const (
+	EventType = "event"
+
+	InvolvedObjectKindField = "involvedObjectKind"
+	InvolvedObjectNameField = "involvedObjectName"
+	InvolvedObjectUIDField  = "involvedObjectUid"
+)
+
+// Fields copied from the involved object, keyed by the field they are copied to
+var involvedObjectFields = map[string]string{
+	InvolvedObjectKindField: "kind",
+	InvolvedObjectNameField: "name",
+	InvolvedObjectUIDField:  "uid",
+}
+
+// Fields is what the event store can select on, keyed by API field
+var Fields = map[string]string{
+	InvolvedObjectKindField: "involvedObject.kind",
+	InvolvedObjectNameField: "involvedObject.name",
+	InvolvedObjectUIDField:  "involvedObject.uid",
+	"reason":                "reason",
+	"eventType":             "type",
+}
+
+type clusterOverride struct {
+	types.Namespaced
+}
+
+type projectOverride struct {
+	types.Namespaced
+	ProjectID string `norman:"type=reference[/v3/schemas/project],noupdate"`
+}
+
+// involvedObjectMapper copies the fields events are looked up by to the top
+// level, where they can be filtered on and passed on as field selectors
+type involvedObjectMapper struct {
+}
+
+func (i involvedObjectMapper) FromInternal(data map[string]interface{}) {
+	for field, from := range involvedObjectFields {
+		if v, ok := values.GetValue(data, "involvedObject", from); ok {
+			data[field] = v
+		}
+	}
+}
+
+func (i involvedObjectMapper) ToInternal(data map[string]interface{}) {
+	for field := range involvedObjectFields {
+		delete(data, field)
+	}
+}
+
+func (i involvedObjectMapper) ModifySchema(schema *types.Schema, schemas *types.Schemas) error {
+	for field := range involvedObjectFields {
+		schema.ResourceFields[field] = types.Field{
+			CodeName: convert.Capitalize(field),
+			Type:     "string",
+		}
+	}
+	return nil
+}
+
+// AddSchemas adds a read only event type to the cluster and project versions.
+// Events in the project version have a projectId, the cluster version has the
+// events of cluster resources like nodes.
+func AddSchemas(schemas *types.Schemas) {
+	addSchema(schemas, &clusterSchema.Version, clusterOverride{})
+	addSchema(schemas, &schema.Version, projectOverride{})
+}
+
+func addSchema(schemas *types.Schemas, version *types.APIVersion, override interface{}) {
+	eventSchemas := factory.Schemas(version).
+		AddSchemas(schemas).
+		AddMapperForType(version, v1.Event{},
+			// type is the type of the resource in the API
+			&m.Move{From: "type", To: "eventType"},
+			involvedObjectMapper{},
+		).
+		MustImportAndCustomize(version, v1.Event{}, func(schema *types.Schema) {
+			schema.CollectionMethods = []string{http.MethodGet}
+			schema.ResourceMethods = []string{http.MethodGet}
+		}, override)
+
+	for _, eventSchema := range eventSchemas.Schemas() {
+		if schemas.Schema(&eventSchema.Version, eventSchema.ID) == nil {
+			schemas.AddSchema(*eventSchema)
+		}
+	}
+}
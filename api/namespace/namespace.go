@@ -1,22 +1,149 @@
 package namespace
 
 import (
-	"github.com/rancher/norman/api/access"
+	"context"
+	"sort"
+	"sync"
+
 	"github.com/rancher/norman/types"
-	"github.com/rancher/types/apis/project.cattle.io/v3/schema"
-	"github.com/rancher/types/client/project/v3"
+	"github.com/rancher/types/apis/core/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	projectIDAnnotation       = "field.cattle.io/projectId"
+	systemNamespaceAnnotation = "management.cattle.io/system-namespace"
+	projectIndex              = "projectId"
 )
 
-func ProjectMap(apiContext *types.APIContext) (map[string]string, error) {
-	var namespaces []client.Namespace
-	if err := access.List(apiContext, &schema.Version, client.NamespaceType, &types.QueryOptions{}, &namespaces); err != nil {
-		return nil, err
+var authContext = map[string]string{
+	"apiGroup": "",
+	"resource": "namespaces",
+}
+
+// ProjectCache answers which project a namespace belongs to from the shared
+// namespace informer instead of listing namespaces on every request
+type ProjectCache struct {
+	namespaces v1.NamespaceLister
+	indexer    cache.Indexer
+
+	lock      sync.Mutex
+	listeners map[chan struct{}]bool
+}
+
+func NewProjectCache(core v1.Interface) *ProjectCache {
+	informer := core.Namespaces("").Controller().Informer()
+	informer.AddIndexers(cache.Indexers{
+		projectIndex: func(obj interface{}) ([]string, error) {
+			if projectID := projectOf(obj); projectID != "" {
+				return []string{projectID}, nil
+			}
+			return nil, nil
+		},
+	})
+
+	p := &ProjectCache{
+		namespaces: core.Namespaces("").Controller().Lister(),
+		indexer:    informer.GetIndexer(),
+		listeners:  map[chan struct{}]bool{},
+	}
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			p.changed()
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if projectOf(oldObj) != projectOf(newObj) {
+				p.changed()
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			p.changed()
+		},
+	})
+
+	return p
+}
+
+// projectOf returns the project of a namespace from the informer, "" for system
+// namespaces and namespaces not in a project
+func projectOf(obj interface{}) string {
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok || ns.Annotations[systemNamespaceAnnotation] == "true" {
+		return ""
+	}
+	return ns.Annotations[projectIDAnnotation]
+}
+
+// Changes returns a channel that receives when a namespace is added, removed or
+// moves between projects, until ctx is done. Changes that happen before the
+// last one is received are sent once.
+func (p *ProjectCache) Changes(ctx context.Context) <-chan struct{} {
+	c := make(chan struct{}, 1)
+
+	p.lock.Lock()
+	p.listeners[c] = true
+	p.lock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		p.lock.Lock()
+		delete(p.listeners, c)
+		p.lock.Unlock()
+	}()
+
+	return c
+}
+
+func (p *ProjectCache) changed() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for c := range p.listeners {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Namespaces returns the names of the namespaces in the project
+func (p *ProjectCache) Namespaces(projectID string) []string {
+	objs, err := p.indexer.ByIndex(projectIndex, projectID)
+	if err != nil {
+		return nil
+	}
+
+	var result []string
+	for _, obj := range objs {
+		result = append(result, obj.(*corev1.Namespace).Name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// InProject reports if the namespace currently belongs to the project
+func (p *ProjectCache) InProject(namespace, projectID string) bool {
+	ns, err := p.namespaces.Get("", namespace)
+	if err != nil || ns.Annotations[systemNamespaceAnnotation] == "true" {
+		return false
+	}
+	return ns.Annotations[projectIDAnnotation] == projectID
+}
+
+// ProjectID returns the project of the namespace, or "" if the namespace does not
+// exist or the caller is not allowed to see it
+func (p *ProjectCache) ProjectID(apiContext *types.APIContext, namespace string) string {
+	ns, err := p.namespaces.Get("", namespace)
+	if err != nil || ns.Annotations[systemNamespaceAnnotation] == "true" {
+		return ""
 	}
 
-	result := map[string]string{}
-	for _, namespace := range namespaces {
-		result[namespace.Name] = namespace.ProjectID
+	if apiContext.AccessControl.Filter(apiContext, map[string]interface{}{
+		"id": ns.Name,
+	}, authContext) == nil {
+		return ""
 	}
 
-	return result, nil
+	return ns.Annotations[projectIDAnnotation]
 }
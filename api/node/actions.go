@@ -0,0 +1,161 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/rancher/cluster-api/auth"
+	"github.com/rancher/cluster-api/k8s"
+	"github.com/rancher/norman/api/access"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/norman/types/values"
+	"github.com/rancher/types/apis/cluster.cattle.io/v3/schema"
+	patchtype "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	CordonAction   = "cordon"
+	UncordonAction = "uncordon"
+	DrainAction    = "drain"
+
+	// The progress of a drain is kept on the node, so clients follow it by
+	// polling or subscribing to the node
+	drainStateAnnotation   = "cattle.io/drain-state"
+	drainMessageAnnotation = "cattle.io/drain-message"
+
+	drainingState    = "draining"
+	drainedState     = "drained"
+	drainFailedState = "failed"
+)
+
+var (
+	patchPermission = auth.Permission{Verb: "patch", Resource: "nodes"}
+	evictPermission = auth.Permission{Verb: "create", Resource: "pods", Subresource: "eviction"}
+)
+
+type DrainInput struct {
+	// Force evicts pods no controller will replace
+	Force bool `json:"force,omitempty"`
+	// GracePeriod in seconds overrides the pods' own when not negative
+	GracePeriod int64 `json:"gracePeriod,omitempty" norman:"default=-1,min=-1"`
+	// DeleteLocalData evicts pods with emptyDir volumes, whose data is lost
+	DeleteLocalData bool `json:"deleteLocalData,omitempty"`
+	// Timeout in seconds after which the drain fails, 0 waits as long as it takes
+	Timeout int64 `json:"timeout,omitempty" norman:"min=0"`
+}
+
+// ConfigureActions adds the maintenance actions to nodes. Drains run in the
+// background until they finish or ctx is done.
+func ConfigureActions(ctx context.Context, k8sClient rest.Interface, schemas *types.Schemas) {
+	schemas.MustImport(&schema.Version, DrainInput{})
+
+	h := &ActionHandler{
+		K8sClient: k8sClient,
+		ctx:       ctx,
+		drains:    map[string]context.CancelFunc{},
+	}
+
+	nodeSchema := schemas.Schema(&schema.Version, "node")
+	nodeSchema.ResourceActions = map[string]types.Action{
+		CordonAction:   {},
+		UncordonAction: {},
+		DrainAction:    {Input: "drainInput"},
+	}
+	nodeSchema.ActionHandler = h.ActionHandler
+	nodeSchema.Formatter = h.Formatter
+}
+
+type ActionHandler struct {
+	K8sClient rest.Interface
+
+	ctx    context.Context
+	lock   sync.Mutex
+	drains map[string]context.CancelFunc
+}
+
+// Formatter adds cordon or uncordon, whichever changes the node, and drain
+// unless one is running
+func (h *ActionHandler) Formatter(apiContext *types.APIContext, resource *types.RawResource) {
+	if !auth.Can(apiContext, patchPermission) {
+		return
+	}
+
+	if convert.ToBool(resource.Values["unschedulable"]) {
+		resource.AddAction(apiContext, UncordonAction)
+	} else {
+		resource.AddAction(apiContext, CordonAction)
+	}
+
+	if values.GetValueN(resource.Values, "annotations", drainStateAnnotation) != drainingState &&
+		auth.Can(apiContext, evictPermission) {
+		resource.AddAction(apiContext, DrainAction)
+	}
+}
+
+func (h *ActionHandler) ActionHandler(actionName string, action *types.Action, apiContext *types.APIContext) error {
+	name := apiContext.ID
+	status := http.StatusOK
+
+	var err error
+	switch actionName {
+	case CordonAction:
+		err = h.patch(apiContext, name, true, nil)
+	case UncordonAction:
+		h.stopDrain(name)
+		err = h.patch(apiContext, name, false, map[string]interface{}{
+			drainStateAnnotation:   nil,
+			drainMessageAnnotation: nil,
+		})
+	case DrainAction:
+		status = http.StatusAccepted
+		err = h.drain(apiContext, name)
+	default:
+		return httperror.NewAPIError(httperror.InvalidAction, fmt.Sprintf("Invalid action: %s", actionName))
+	}
+	if err != nil {
+		return k8s.TranslateError(err)
+	}
+
+	var data map[string]interface{}
+	if err := access.ByID(apiContext, apiContext.Version, apiContext.Type, apiContext.ID, &data); err != nil {
+		return err
+	}
+	apiContext.WriteResponse(status, data)
+	return nil
+}
+
+// patch sets whether the node is unschedulable along with the given annotations,
+// a nil annotation is removed
+func (h *ActionHandler) patch(apiContext *types.APIContext, name string, unschedulable bool, annotations map[string]interface{}) error {
+	patch := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"unschedulable": unschedulable,
+		},
+	}
+	// A null annotations field would remove all of them
+	if annotations != nil {
+		patch["metadata"] = map[string]interface{}{
+			"annotations": annotations,
+		}
+	}
+	return patchNode(apiContext.Request.Context(), h.K8sClient, apiContext.Request.Header, name, patch)
+}
+
+func patchNode(ctx context.Context, k8sClient rest.Interface, header http.Header, name string, patch map[string]interface{}) error {
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	return k8s.Request(ctx, k8sClient, header, http.MethodPatch, k8s.CoreV1, "", "nodes", name).
+		SetHeader("Content-Type", string(patchtype.MergePatchType)).
+		Body(body).
+		Do().
+		Error()
+}
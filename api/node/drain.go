@@ -0,0 +1,347 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/cluster-api/k8s"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/parse"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+	// evictionRetryDelay is how long to wait when a disruption budget doesn't
+	// allow an eviction yet
+	evictionRetryDelay = 5 * time.Second
+	deletePollInterval = time.Second
+)
+
+// drainer evicts the pods of a node as the caller that started the drain
+type drainer struct {
+	k8sClient   rest.Interface
+	header      http.Header
+	node        string
+	pods        []corev1.Pod
+	gracePeriod int64
+
+	// lock keeps progress in order
+	lock    sync.Mutex
+	evicted int
+}
+
+func (h *ActionHandler) drain(apiContext *types.APIContext, name string) error {
+	input, err := parse.ReadBody(apiContext.Request)
+	if err != nil {
+		return err
+	}
+
+	gracePeriod := int64(-1)
+	if input["gracePeriod"] != nil {
+		gracePeriod, err = convert.ToNumber(input["gracePeriod"])
+		if err != nil || gracePeriod < -1 {
+			return httperror.NewFieldAPIError(httperror.InvalidFormat, "gracePeriod", "must be a number of at least -1")
+		}
+	}
+	timeout, err := convert.ToNumber(input["timeout"])
+	if input["timeout"] != nil && (err != nil || timeout < 0) {
+		return httperror.NewFieldAPIError(httperror.InvalidFormat, "timeout", "must be a number of at least 0")
+	}
+
+	ctx, cancel, ok := h.reserveDrain(name, time.Duration(timeout)*time.Second)
+	if !ok {
+		return httperror.NewAPIError(httperror.InvalidState, "node is already being drained")
+	}
+
+	pods, err := h.podsToEvict(apiContext, name, convert.ToBool(input["force"]), convert.ToBool(input["deleteLocalData"]))
+	if err == nil {
+		err = h.patch(apiContext, name, true, map[string]interface{}{
+			drainStateAnnotation:   drainingState,
+			drainMessageAnnotation: fmt.Sprintf("Evicting %d pods", len(pods)),
+		})
+	}
+	if err == nil && h.uncordoned(ctx) {
+		// The uncordon may have come before the patch above, which cordoned the
+		// node again, so it is undone
+		err = h.patch(apiContext, name, false, map[string]interface{}{
+			drainStateAnnotation:   nil,
+			drainMessageAnnotation: nil,
+		})
+		if err == nil {
+			err = httperror.NewAPIError(httperror.InvalidState, "node was uncordoned before the drain started")
+		}
+	}
+	if err != nil {
+		cancel()
+		h.releaseDrain(name)
+		return err
+	}
+
+	d := &drainer{
+		k8sClient: h.K8sClient,
+		// The drain outlives the request, so it keeps its own copy of who made it
+		header:      k8s.Identity(apiContext.Request.Header),
+		node:        name,
+		pods:        pods,
+		gracePeriod: gracePeriod,
+	}
+	h.startDrain(ctx, cancel, d)
+	return nil
+}
+
+// podsToEvict lists the pods on the node that a drain evicts. It fails, like
+// kubectl drain, when pods would be lost that force or deleteLocalData allow.
+func (h *ActionHandler) podsToEvict(apiContext *types.APIContext, name string, force, deleteLocalData bool) ([]corev1.Pod, error) {
+	list := &corev1.PodList{}
+	if err := k8s.CallerRequest(apiContext, h.K8sClient, http.MethodGet, k8s.CoreV1, "", "pods", "").
+		Param("fieldSelector", fields.OneTermEqualSelector("spec.nodeName", name).String()).
+		Do().
+		Into(list); err != nil {
+		return nil, err
+	}
+
+	var (
+		result    []corev1.Pod
+		unmanaged []string
+		localData []string
+	)
+	for _, pod := range list.Items {
+		// Mirror pods belong to the kubelet, and daemon set pods would be
+		// scheduled on the node again
+		if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+			continue
+		}
+		controller := metav1.GetControllerOf(&pod)
+		if controller != nil && controller.Kind == "DaemonSet" {
+			continue
+		}
+
+		finished := pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+		if controller == nil && !finished && !force {
+			unmanaged = append(unmanaged, pod.Namespace+"/"+pod.Name)
+		}
+		if hasLocalData(&pod) && !finished && !deleteLocalData {
+			localData = append(localData, pod.Namespace+"/"+pod.Name)
+		}
+
+		result = append(result, pod)
+	}
+
+	if len(unmanaged) > 0 {
+		return nil, httperror.NewFieldAPIError(httperror.InvalidState, "force",
+			"pods not managed by a controller would be lost: "+strings.Join(unmanaged, ", "))
+	}
+	if len(localData) > 0 {
+		return nil, httperror.NewFieldAPIError(httperror.InvalidState, "deleteLocalData",
+			"pods with local storage would lose their data: "+strings.Join(localData, ", "))
+	}
+
+	return result, nil
+}
+
+func hasLocalData(pod *corev1.Pod) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// startDrain runs d in the background until ctx, the context of its reservation,
+// is done
+func (h *ActionHandler) startDrain(ctx context.Context, cancel context.CancelFunc, d *drainer) {
+	go func() {
+		defer func() {
+			h.releaseDrain(d.node)
+			cancel()
+		}()
+
+		err := d.run(ctx)
+		if h.uncordoned(ctx) {
+			// The uncordon already cleared the annotations
+			return
+		}
+
+		annotations := map[string]interface{}{
+			drainStateAnnotation:   drainedState,
+			drainMessageAnnotation: nil,
+		}
+		if err != nil {
+			annotations[drainStateAnnotation] = drainFailedState
+			annotations[drainMessageAnnotation] = err.Error()
+		}
+		// ctx may be done, but the result still needs to be recorded
+		patchNode(context.Background(), d.k8sClient, d.header, d.node, map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": annotations,
+			},
+		})
+	}()
+}
+
+// reserveDrain marks the node as being drained, unless it already is. The drain
+// runs in the returned context, which ends with the server, when it times out,
+// or when the node is uncordoned.
+func (h *ActionHandler) reserveDrain(name string, timeout time.Duration) (context.Context, context.CancelFunc, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if _, ok := h.drains[name]; ok {
+		return nil, nil, false
+	}
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(h.ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(h.ctx)
+	}
+	h.drains[name] = cancel
+	return ctx, cancel, true
+}
+
+// uncordoned reports if the drain running in ctx was stopped by an uncordon
+func (h *ActionHandler) uncordoned(ctx context.Context) bool {
+	return ctx.Err() == context.Canceled && h.ctx.Err() == nil
+}
+
+func (h *ActionHandler) releaseDrain(name string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.drains, name)
+}
+
+// stopDrain cancels the drain of the node, it is released once it has stopped
+func (h *ActionHandler) stopDrain(name string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if cancel, ok := h.drains[name]; ok {
+		cancel()
+	}
+}
+
+// run evicts all pods at once, so a pod a disruption budget holds back doesn't
+// hold back the others
+func (d *drainer) run(ctx context.Context) error {
+	var (
+		wg       sync.WaitGroup
+		lock     sync.Mutex
+		failures []string
+	)
+
+	for i := range d.pods {
+		pod := &d.pods[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := d.evict(ctx, pod); err != nil {
+				lock.Lock()
+				failures = append(failures, fmt.Sprintf("%s/%s: %v", pod.Namespace, pod.Name, err))
+				lock.Unlock()
+				return
+			}
+			d.progress(ctx)
+		}()
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to evict %s", strings.Join(failures, ", "))
+	}
+	return nil
+}
+
+// evict evicts pod, retrying while disruption budgets don't allow it, and waits
+// for it to be deleted
+func (d *drainer) evict(ctx context.Context, pod *corev1.Pod) error {
+	eviction := &policyv1beta1.Eviction{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "policy/v1beta1",
+			Kind:       "Eviction",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+	}
+	if d.gracePeriod >= 0 {
+		eviction.DeleteOptions = &metav1.DeleteOptions{
+			GracePeriodSeconds: &d.gracePeriod,
+		}
+	}
+	body, err := json.Marshal(eviction)
+	if err != nil {
+		return err
+	}
+
+	for {
+		err := k8s.Request(ctx, d.k8sClient, d.header, http.MethodPost, k8s.CoreV1, pod.Namespace, "pods", pod.Name).
+			SubResource("eviction").
+			Body(body).
+			Do().
+			Error()
+		if err == nil || errors.IsNotFound(err) {
+			break
+		}
+		if !errors.IsTooManyRequests(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("disruption budget did not allow eviction: %v", ctx.Err())
+		case <-time.After(evictionRetryDelay):
+		}
+	}
+
+	for {
+		current := &corev1.Pod{}
+		err := k8s.Request(ctx, d.k8sClient, d.header, http.MethodGet, k8s.CoreV1, pod.Namespace, "pods", pod.Name).
+			Do().
+			Into(current)
+		if errors.IsNotFound(err) || (err == nil && current.UID != pod.UID) {
+			return nil
+		}
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("pod was not deleted: %v", ctx.Err())
+		case <-time.After(deletePollInterval):
+		}
+	}
+}
+
+// progress counts an evicted pod and records the count on the node
+func (d *drainer) progress(ctx context.Context) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.evicted++
+	patchNode(ctx, d.k8sClient, d.header, d.node, map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				drainMessageAnnotation: fmt.Sprintf("Evicted %d of %d pods", d.evicted, len(d.pods)),
+			},
+		},
+	})
+}
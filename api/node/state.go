@@ -0,0 +1,40 @@
+package node
+
+import (
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/norman/types/values"
+)
+
+func Transform(context *types.APIContext, data map[string]interface{}) (map[string]interface{}, error) {
+	if data == nil {
+		return data, nil
+	}
+	return setState(data), nil
+}
+
+// setState shows cordoned nodes, and the drain of one, in their state. The
+// conditions the status mapper looks at only tell whether the node is healthy.
+func setState(data map[string]interface{}) map[string]interface{} {
+	if data["state"] == "removing" || !convert.ToBool(data["unschedulable"]) {
+		return data
+	}
+
+	message := convert.ToString(values.GetValueN(data, "annotations", drainMessageAnnotation))
+	state, transitioning := "cordoned", "no"
+	switch values.GetValueN(data, "annotations", drainStateAnnotation) {
+	case drainingState:
+		state, transitioning = drainingState, "yes"
+	case drainedState:
+		state = drainedState
+	case drainFailedState:
+		transitioning = "error"
+	default:
+		message = ""
+	}
+
+	data["state"] = state
+	data["transitioning"] = transitioning
+	data["transitioningMessage"] = message
+	return data
+}
@@ -0,0 +1,136 @@
+package pod
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/cluster-api/auth"
+	"github.com/rancher/cluster-api/k8s"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/rest"
+)
+
+// headerCapture records the headers client-go would send, so a websocket dial
+// authenticates the same way the rest client does
+type headerCapture struct {
+	header http.Header
+}
+
+func (h *headerCapture) RoundTrip(req *http.Request) (*http.Response, error) {
+	h.header = req.Header
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(&bytes.Buffer{}),
+		Request:    req,
+	}, nil
+}
+
+func authHeaders(config *rest.Config, u *url.URL) (http.Header, error) {
+	capture := &headerCapture{}
+	rt, err := rest.HTTPWrappersForConfig(config, capture)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	return capture.header, nil
+}
+
+// proxyWebsocket dials the apiserver websocket at u with the caller's identity and
+// relays frames both ways. The subprotocol requested by the client is passed on
+// untouched, so the client talks the channel protocol of the apiserver directly.
+func proxyWebsocket(apiContext *types.APIContext, config *rest.Config, u *url.URL, defaultProtocol string) error {
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+
+	header, err := authHeaders(config, u)
+	if err != nil {
+		return err
+	}
+	for _, name := range k8s.ImpersonationHeaders {
+		if values := apiContext.Request.Header[http.CanonicalHeaderKey(name)]; len(values) > 0 {
+			header[http.CanonicalHeaderKey(name)] = values
+		} else {
+			header.Del(name)
+		}
+	}
+
+	tlsConfig, err := rest.TLSConfigFor(config)
+	if err != nil {
+		return err
+	}
+
+	protocols := websocket.Subprotocols(apiContext.Request)
+	if len(protocols) == 0 {
+		protocols = []string{defaultProtocol}
+	}
+
+	dialer := &websocket.Dialer{
+		TLSClientConfig: tlsConfig,
+		Subprotocols:    protocols,
+	}
+
+	backend, resp, err := dialer.Dial(u.String(), header)
+	if err != nil {
+		if resp != nil {
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			return httperror.NewAPIErrorLong(resp.StatusCode, http.StatusText(resp.StatusCode), string(body))
+		}
+		return err
+	}
+	defer backend.Close()
+
+	// The backend may have picked the default protocol the client didn't offer
+	client, err := upgrader.Upgrade(apiContext.Response, apiContext.Request,
+		auth.WebsocketResponseHeader(apiContext.Request, backend.Subprotocol()))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	done := make(chan struct{}, 2)
+	go relay(client, backend, done)
+	go relay(backend, client, done)
+	<-done
+
+	return nil
+}
+
+func relay(from, to *websocket.Conn, done chan struct{}) {
+	defer func() {
+		done <- struct{}{}
+	}()
+
+	for {
+		messageType, data, err := from.ReadMessage()
+		if err != nil {
+			logrus.Debugf("Closing websocket relay: %v", err)
+			to.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		}
+
+		if err := to.WriteMessage(messageType, data); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package pod
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+)
+
+// v4 of the channel protocol adds the resize channel and structured exit errors
+const channelProtocol = "v4.channel.k8s.io"
+
+func (l *LinkHandler) exec(apiContext *types.APIContext, namespace, name string) error {
+	query := apiContext.Request.URL.Query()
+	if len(query["command"]) == 0 {
+		return httperror.NewFieldAPIError(httperror.MissingRequired, "command", "")
+	}
+
+	return l.stream(apiContext, namespace, name, "exec")
+}
+
+func (l *LinkHandler) attach(apiContext *types.APIContext, namespace, name string) error {
+	return l.stream(apiContext, namespace, name, "attach")
+}
+
+func (l *LinkHandler) stream(apiContext *types.APIContext, namespace, name, subResource string) error {
+	if !isWebsocket(apiContext.Request) {
+		return httperror.NewAPIError(httperror.InvalidAction, subResource+" requires a websocket")
+	}
+
+	query := apiContext.Request.URL.Query()
+	req := l.podRequest(apiContext, http.MethodGet, namespace, name, subResource)
+
+	if subResource == "exec" {
+		for _, command := range query["command"] {
+			req.Param("command", command)
+		}
+	}
+
+	if container := query.Get("container"); container != "" {
+		req.Param("container", container)
+	}
+
+	for param, def := range map[string]string{
+		"stdin":  "false",
+		"stdout": "true",
+		"stderr": "true",
+		"tty":    "false",
+	} {
+		value := query.Get(param)
+		if value == "" {
+			value = def
+		} else if _, err := strconv.ParseBool(value); err != nil {
+			return httperror.NewFieldAPIError(httperror.InvalidFormat, param, "must be true or false")
+		}
+		req.Param(param, value)
+	}
+
+	return proxyWebsocket(apiContext, l.Config, req.URL(), channelProtocol)
+}
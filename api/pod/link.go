@@ -0,0 +1,73 @@
+package pod
+
+import (
+	"strings"
+
+	"github.com/rancher/cluster-api/auth"
+	"github.com/rancher/cluster-api/k8s"
+	"github.com/rancher/norman/api/access"
+	"github.com/rancher/norman/api/handler"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	"k8s.io/client-go/rest"
+)
+
+type LinkHandler struct {
+	K8sClient rest.Interface
+	Config    *rest.Config
+}
+
+var linkPermissions = map[string]auth.Permission{
+	"logs":        {Verb: "get", Resource: "pods", Subresource: "log"},
+	"exec":        {Verb: "create", Resource: "pods", Subresource: "exec"},
+	"attach":      {Verb: "create", Resource: "pods", Subresource: "attach"},
+	"portforward": {Verb: "create", Resource: "pods", Subresource: "portforward"},
+}
+
+func (l *LinkHandler) Formatter(apiContext *types.APIContext, resource *types.RawResource) {
+	namespace, _ := resource.Values["namespaceId"].(string)
+	for link, permission := range linkPermissions {
+		permission.Namespace = namespace
+		if auth.Can(apiContext, permission) {
+			resource.Links[link] = apiContext.URLBuilder.Link(link, resource)
+		}
+	}
+}
+
+// ListHandler serves the pod links, norman sends link requests to the list handler
+func (l *LinkHandler) ListHandler(apiContext *types.APIContext) error {
+	switch apiContext.Link {
+	case "":
+		return handler.ListHandler(apiContext)
+	case "logs":
+		return WithResource(apiContext, l.logs)
+	case "exec":
+		return WithResource(apiContext, l.exec)
+	case "attach":
+		return WithResource(apiContext, l.attach)
+	case "portforward":
+		return WithResource(apiContext, l.portForward)
+	default:
+		return apiContext.Schema.LinkHandler(apiContext)
+	}
+}
+
+// WithResource calls next with the namespace and name of the resource the request
+// is for, once the resource is known to be visible in the current project
+func WithResource(apiContext *types.APIContext, next func(apiContext *types.APIContext, namespace, name string) error) error {
+	if err := access.ByID(apiContext, apiContext.Version, apiContext.Type, apiContext.ID, nil); err != nil {
+		return err
+	}
+
+	parts := strings.SplitN(apiContext.ID, ":", 2)
+	if len(parts) != 2 {
+		return httperror.NewAPIError(httperror.NotFound, "invalid id "+apiContext.ID)
+	}
+
+	return next(apiContext, parts[0], parts[1])
+}
+
+func (l *LinkHandler) podRequest(apiContext *types.APIContext, method, namespace, name, subResource string) *rest.Request {
+	return k8s.CallerRequest(apiContext, l.K8sClient, method, k8s.CoreV1, namespace, "pods", name).
+		SubResource(subResource)
+}
@@ -0,0 +1,132 @@
+package pod
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/cluster-api/auth"
+	"github.com/rancher/cluster-api/k8s"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+)
+
+var upgrader = websocket.Upgrader{}
+
+func (l *LinkHandler) logs(apiContext *types.APIContext, namespace, name string) error {
+	req := l.podRequest(apiContext, http.MethodGet, namespace, name, "log")
+
+	query := apiContext.Request.URL.Query()
+	if container := query.Get("container"); container != "" {
+		req.Param("container", container)
+	}
+
+	for _, param := range []string{"follow", "previous"} {
+		if value := query.Get(param); value != "" {
+			if _, err := strconv.ParseBool(value); err != nil {
+				return httperror.NewFieldAPIError(httperror.InvalidFormat, param, "must be true or false")
+			}
+			req.Param(param, value)
+		}
+	}
+
+	for _, param := range []string{"tailLines", "sinceSeconds"} {
+		if value := query.Get(param); value != "" {
+			if i, err := strconv.ParseInt(value, 10, 64); err != nil || i < 0 {
+				return httperror.NewFieldAPIError(httperror.InvalidFormat, param, "must be a positive number")
+			}
+			req.Param(param, value)
+		}
+	}
+
+	stream, err := req.Stream()
+	if err != nil {
+		return k8s.TranslateError(err)
+	}
+	defer stream.Close()
+
+	if isWebsocket(apiContext.Request) {
+		return streamWebsocket(apiContext, stream)
+	}
+
+	apiContext.Response.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	apiContext.Response.WriteHeader(http.StatusOK)
+
+	_, err = io.Copy(&flushWriter{writer: apiContext.Response}, stream)
+	return ignoreClosed(apiContext, err)
+}
+
+// streamWebsocket sends the logs in binary messages, like the exec and attach
+// channels. A read can end in the middle of a UTF-8 sequence, which browsers
+// close the connection over in a text message.
+func streamWebsocket(apiContext *types.APIContext, stream io.ReadCloser) error {
+	c, err := upgrader.Upgrade(apiContext.Response, apiContext.Request, auth.WebsocketResponseHeader(apiContext.Request))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	// Only read to notice the client going away. The request context isn't
+	// cancelled for a hijacked connection, so a followed stream is closed to end
+	// the read below.
+	gone := make(chan struct{})
+	go func() {
+		for {
+			if _, _, err := c.NextReader(); err != nil {
+				close(gone)
+				stream.Close()
+				c.Close()
+				return
+			}
+		}
+	}()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := stream.Read(buf)
+		if n > 0 {
+			if err := c.WriteMessage(websocket.BinaryMessage, buf[:n]); err != nil {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		}
+		if err != nil {
+			select {
+			case <-gone:
+				return nil
+			default:
+				return ignoreClosed(apiContext, err)
+			}
+		}
+	}
+}
+
+// ignoreClosed drops the error caused by the client disconnecting, after the
+// response has started there is no way to report it anyway
+func ignoreClosed(apiContext *types.APIContext, err error) error {
+	if apiContext.Request.Context().Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func isWebsocket(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(req.Header.Get("Connection")), "upgrade")
+}
+
+type flushWriter struct {
+	writer http.ResponseWriter
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.writer.Write(p)
+	if flusher, ok := f.writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
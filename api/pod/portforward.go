@@ -0,0 +1,36 @@
+package pod
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+)
+
+func (l *LinkHandler) portForward(apiContext *types.APIContext, namespace, name string) error {
+	return l.PortForward(apiContext, namespace, name, apiContext.Request.URL.Query()["ports"])
+}
+
+// PortForward tunnels the given pod ports over a websocket. Every port gets a data
+// and an error channel in the order the ports are listed, as in the apiserver
+// port forward protocol.
+func (l *LinkHandler) PortForward(apiContext *types.APIContext, namespace, name string, ports []string) error {
+	if !isWebsocket(apiContext.Request) {
+		return httperror.NewAPIError(httperror.InvalidAction, "portforward requires a websocket")
+	}
+
+	if len(ports) == 0 {
+		return httperror.NewFieldAPIError(httperror.MissingRequired, "ports", "")
+	}
+
+	req := l.podRequest(apiContext, http.MethodGet, namespace, name, "portforward")
+	for _, port := range ports {
+		if i, err := strconv.Atoi(port); err != nil || i <= 0 || i > 65535 {
+			return httperror.NewFieldAPIError(httperror.InvalidFormat, "ports", "invalid port "+port)
+		}
+		req.Param("ports", port)
+	}
+
+	return proxyWebsocket(apiContext, l.Config, req.URL(), channelProtocol)
+}
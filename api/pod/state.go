@@ -0,0 +1,238 @@
+package pod
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rancher/cluster-api/api/workload"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/norman/types/values"
+	"github.com/rancher/types/apis/project.cattle.io/v3/schema"
+	"github.com/rancher/types/client/project/v3"
+)
+
+const (
+	RestartCountField    = "restartCount"
+	ContainerStatesField = "containerStates"
+
+	containerSummaryType = "containerSummary"
+)
+
+// ContainerSummary summarizes the status of one container of a pod
+type ContainerSummary struct {
+	Name          string `json:"name,omitempty"`
+	InitContainer bool   `json:"initContainer,omitempty"`
+	// State is running, waiting or terminated
+	State        string `json:"state,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	Message      string `json:"message,omitempty"`
+	ExitCode     int64  `json:"exitCode,omitempty"`
+	Ready        bool   `json:"ready,omitempty"`
+	RestartCount int64  `json:"restartCount,omitempty"`
+	// LastReason is why the container last terminated, like OOMKilled
+	LastReason string `json:"lastReason,omitempty"`
+}
+
+// ConfigureSchema adds the fields the transformer computes from the container
+// statuses to the pod schema
+func ConfigureSchema(schemas *types.Schemas) {
+	schemas.MustImportAndCustomize(&schema.Version, ContainerSummary{}, func(schema *types.Schema) {
+		schema.CollectionMethods = []string{}
+		schema.ResourceMethods = []string{}
+	})
+
+	podSchema := schemas.Schema(&schema.Version, client.PodType)
+	podSchema.ResourceFields[RestartCountField] = types.Field{
+		CodeName: "RestartCount",
+		Type:     "int",
+	}
+	podSchema.ResourceFields[ContainerStatesField] = types.Field{
+		CodeName: "ContainerStates",
+		Type:     "array[" + containerSummaryType + "]",
+	}
+}
+
+// setState sets state, transitioning and transitioningMessage the way kubectl
+// describes a pod, along with the restart count and a summary of each container
+func setState(data map[string]interface{}) map[string]interface{} {
+	status, ok := data["status"].(map[string]interface{})
+	if !ok {
+		return data
+	}
+
+	initStates := containerStates(status["initContainerStatuses"], true)
+	states := containerStates(status["containerStatuses"], false)
+
+	var restartCount int64
+	var summary []interface{}
+	for _, state := range append(initStates, states...) {
+		restartCount += state.RestartCount
+		summary = append(summary, toMap(state))
+	}
+	data[RestartCountField] = restartCount
+	data[ContainerStatesField] = summary
+
+	if data["state"] == "removing" {
+		return data
+	}
+
+	phase := convert.ToString(status["phase"])
+	state, transitioning, message := strings.ToLower(phase), "no", ""
+	if state == "" {
+		state = "pending"
+	}
+
+	switch phase {
+	case "Succeeded":
+	case "Failed":
+		transitioning, message = "error", join(convert.ToString(status["reason"]), convert.ToString(status["message"]))
+	default:
+		if message, failed := initializing(initStates, initContainerCount(data, initStates)); message != "" {
+			transitioning := "yes"
+			if failed {
+				transitioning = "error"
+			}
+			return set(data, "initializing", transitioning, message)
+		}
+
+		if problem := unschedulable(status); problem != "" {
+			return set(data, state, "error", problem)
+		}
+
+		for _, container := range states {
+			if !workload.StartingReason(container.Reason) && container.Reason != "Completed" {
+				message := join(container.Reason, container.Message)
+				if container.LastReason != "" {
+					message += " (last terminated: " + container.LastReason + ")"
+				}
+				return set(data, state, "error", message)
+			}
+		}
+
+		if notReady := notReady(states); len(notReady) > 0 {
+			transitioning, message = "yes", "Containers not ready: "+strings.Join(notReady, ", ")
+		} else if phase != "Running" {
+			transitioning, message = "yes", convert.ToString(status["message"])
+		}
+	}
+
+	return set(data, state, transitioning, message)
+}
+
+func set(data map[string]interface{}, state, transitioning, message string) map[string]interface{} {
+	data["state"] = state
+	data["transitioning"] = transitioning
+	data["transitioningMessage"] = message
+	return data
+}
+
+func containerStates(statuses interface{}, init bool) []ContainerSummary {
+	var result []ContainerSummary
+	for _, status := range convert.ToMapSlice(statuses) {
+		restartCount, _ := convert.ToNumber(status["restartCount"])
+		state := ContainerSummary{
+			Name:          convert.ToString(status["name"]),
+			InitContainer: init,
+			Ready:         convert.ToBool(status["ready"]),
+			RestartCount:  restartCount,
+			LastReason:    convert.ToString(values.GetValueN(status, "lastState", "terminated", "reason")),
+		}
+
+		for _, name := range []string{"running", "waiting", "terminated"} {
+			detail, ok := values.GetValueN(status, "state", name).(map[string]interface{})
+			if !ok {
+				continue
+			}
+			state.State = name
+			state.Reason = convert.ToString(detail["reason"])
+			state.Message = convert.ToString(detail["message"])
+			if name == "terminated" {
+				state.ExitCode, _ = convert.ToNumber(detail["exitCode"])
+				if state.Reason == "" {
+					state.Reason = terminatedReason(detail)
+				}
+			}
+			break
+		}
+
+		result = append(result, state)
+	}
+	return result
+}
+
+// terminatedReason is what kubectl shows for a container that exited without a reason
+func terminatedReason(detail map[string]interface{}) string {
+	exitCode, _ := convert.ToNumber(detail["exitCode"])
+	if exitCode == 0 {
+		return "Completed"
+	}
+	if signal, _ := convert.ToNumber(detail["signal"]); signal != 0 {
+		return fmt.Sprintf("Signal:%d", signal)
+	}
+	return fmt.Sprintf("ExitCode:%d", exitCode)
+}
+
+// initializing describes the init containers while they haven't all completed,
+// either as the progress or as Init:<reason> when one fails
+func initializing(states []ContainerSummary, count int) (string, bool) {
+	for i, state := range states {
+		switch {
+		case state.State == "terminated" && state.ExitCode == 0:
+			continue
+		case state.State == "terminated", !workload.StartingReason(state.Reason):
+			return join("Init:"+state.Reason, state.Message), true
+		default:
+			return fmt.Sprintf("Init:%d/%d", i, count), false
+		}
+	}
+	return "", false
+}
+
+func initContainerCount(data map[string]interface{}, states []ContainerSummary) int {
+	count := 0
+	for _, container := range convert.ToMapSlice(data[client.PodFieldContainers]) {
+		if convert.ToBool(container["initContainer"]) {
+			count++
+		}
+	}
+	if count < len(states) {
+		return len(states)
+	}
+	return count
+}
+
+func unschedulable(status map[string]interface{}) string {
+	for _, condition := range convert.ToMapSlice(status["conditions"]) {
+		if condition["type"] == "PodScheduled" && condition["status"] == "False" && condition["reason"] == "Unschedulable" {
+			return convert.ToString(condition["message"])
+		}
+	}
+	return ""
+}
+
+func notReady(states []ContainerSummary) []string {
+	var result []string
+	for _, state := range states {
+		if !state.Ready && state.Reason != "Completed" {
+			result = append(result, state.Name)
+		}
+	}
+	return result
+}
+
+func toMap(state ContainerSummary) map[string]interface{} {
+	result, _ := convert.EncodeToMap(state)
+	result["type"] = containerSummaryType
+	return result
+}
+
+func join(reason, message string) string {
+	if message == "" {
+		return reason
+	}
+	if reason == "" {
+		return message
+	}
+	return reason + ": " + message
+}
@@ -0,0 +1,62 @@
+package pod
+
+import (
+	"testing"
+
+	"github.com/rancher/norman/parse/builder"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/types/apis/project.cattle.io/v3/schema"
+	"github.com/rancher/types/client/project/v3"
+)
+
+func TestContainerStatesKeepSummaryFields(t *testing.T) {
+	schemas := types.NewSchemas().AddSchemas(schema.Schemas)
+	ConfigureSchema(schemas)
+
+	data := setState(map[string]interface{}{
+		"type": client.PodType,
+		"status": map[string]interface{}{
+			"phase": "Running",
+			"containerStatuses": []interface{}{
+				map[string]interface{}{
+					"name":         "web",
+					"ready":        false,
+					"restartCount": int64(3),
+					"state": map[string]interface{}{
+						"waiting": map[string]interface{}{
+							"reason": "CrashLoopBackOff",
+						},
+					},
+				},
+			},
+		},
+	})
+
+	apiContext := &types.APIContext{
+		Version: &schema.Version,
+		Schemas: schemas,
+	}
+	podSchema := schemas.Schema(&schema.Version, client.PodType)
+	result, err := builder.NewBuilder(apiContext).Construct(podSchema, data, builder.List)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	states := convert.ToMapSlice(result[ContainerStatesField])
+	if len(states) != 1 {
+		t.Fatalf("expected 1 container state, got %v", result[ContainerStatesField])
+	}
+
+	expected := map[string]string{
+		"name":         "web",
+		"state":        "waiting",
+		"reason":       "CrashLoopBackOff",
+		"restartCount": "3",
+	}
+	for field, value := range expected {
+		if actual := convert.ToString(states[0][field]); actual != value {
+			t.Errorf("expected %s to be %q, got %q", field, value, actual)
+		}
+	}
+}
@@ -1,27 +1,30 @@
 package pod
 
 import (
-	"strings"
-
 	"github.com/rancher/cluster-api/api/workload"
 	"github.com/rancher/norman/types"
-	"github.com/rancher/norman/types/definition"
+	"github.com/rancher/norman/types/convert"
 )
 
-func Transform(context *types.APIContext, data map[string]interface{}) (map[string]interface{}, error) {
+type Transformer struct {
+	Owners *workload.OwnerCache
+}
+
+func (t *Transformer) Transform(context *types.APIContext, data map[string]interface{}) (map[string]interface{}, error) {
 	if data == nil {
 		return data, nil
 	}
-	mapping, err := workload.OwnerMap(context)
-	if err != nil {
-		return nil, err
-	}
 
-	return assignID(data, mapping), nil
+	return t.transform(context, data), nil
 }
 
-func assignID(data map[string]interface{}, mapping map[string]string) map[string]interface{} {
-	owner := workload.ResolveWorkloadID(data, mapping)
+// transform is every change the transformer makes to a pod
+func (t *Transformer) transform(context *types.APIContext, data map[string]interface{}) map[string]interface{} {
+	return setState(t.assignID(context, data))
+}
+
+func (t *Transformer) assignID(context *types.APIContext, data map[string]interface{}) map[string]interface{} {
+	owner := t.Owners.ResolveWorkloadID(context, data)
 	if owner != "" {
 		data["workloadId"] = owner
 	}
@@ -29,41 +32,17 @@ func assignID(data map[string]interface{}, mapping map[string]string) map[string
 	return data
 }
 
-func ListTransform(context *types.APIContext, data []map[string]interface{}) ([]map[string]interface{}, error) {
-	mapping, err := workload.OwnerMap(context)
-	if err != nil {
-		return nil, err
-	}
-
+func (t *Transformer) ListTransform(context *types.APIContext, data []map[string]interface{}) ([]map[string]interface{}, error) {
 	var result []map[string]interface{}
 	for _, item := range data {
-		result = append(result, assignID(item, mapping))
+		result = append(result, t.transform(context, item))
 	}
 
 	return result, nil
 }
 
-func StreamTransform(context *types.APIContext, data chan map[string]interface{}) (chan map[string]interface{}, error) {
-	mapping, err := workload.OwnerMap(context)
-	if err != nil {
-		return nil, err
-	}
-
-	result := make(chan map[string]interface{})
-	go func() {
-		for item := range data {
-			typeName := definition.GetType(item)
-			if strings.Contains(typeName, "replica") || strings.Contains(typeName, "deployment") {
-				tempMapping, err := workload.OwnerMap(context)
-				if err == nil {
-					mapping = tempMapping
-				}
-			}
-
-			result <- assignID(item, mapping)
-		}
-		close(result)
-	}()
-
-	return result, nil
+func (t *Transformer) StreamTransform(context *types.APIContext, data chan map[string]interface{}) (chan map[string]interface{}, error) {
+	return convert.Chan(data, func(item map[string]interface{}) map[string]interface{} {
+		return t.transform(context, item)
+	}), nil
 }
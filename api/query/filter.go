@@ -0,0 +1,179 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/norman/api/handler"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+)
+
+// Filter is a types.QueryFilter that sorts stably and pages with continue
+// tokens, so the order and the pages of a list do not change between requests
+// unless the data does. It is used for aggregated workloads, whose list is put
+// together from every workload type.
+func Filter(opts *types.QueryOptions, data []map[string]interface{}) []map[string]interface{} {
+	data = handler.ApplyQueryConditions(opts.Conditions, data)
+	data = Sort(opts.Sort, data)
+	return Paginate(opts.Sort, opts.Pagination, data)
+}
+
+// Less orders by the sort field and then by id, which is unique within a list.
+func Less(sortOpts types.Sort, left, right map[string]interface{}) bool {
+	if sortOpts.Order == types.DESC {
+		left, right = right, left
+	}
+
+	name := sortField(sortOpts)
+	if c := compare(left[name], right[name]); c != 0 {
+		return c < 0
+	}
+	return convert.ToString(left["id"]) < convert.ToString(right["id"])
+}
+
+// compare orders numbers by value and everything else as strings. The values of
+// a continue token are strings, they are compared as numbers against numbers.
+func compare(left, right interface{}) int {
+	leftNumber, leftOK := number(left)
+	rightNumber, rightOK := number(right)
+	if leftOK && !rightOK {
+		rightNumber, rightOK = parseNumber(right)
+	} else if rightOK && !leftOK {
+		leftNumber, leftOK = parseNumber(left)
+	}
+
+	if leftOK && rightOK {
+		switch {
+		case leftNumber < rightNumber:
+			return -1
+		case leftNumber > rightNumber:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(convert.ToString(left), convert.ToString(right))
+}
+
+func number(value interface{}) (float64, bool) {
+	switch value.(type) {
+	case int, int32, int64, float32, float64:
+		return parseNumber(value)
+	}
+	return 0, false
+}
+
+func parseNumber(value interface{}) (float64, bool) {
+	n, err := strconv.ParseFloat(convert.ToString(value), 64)
+	return n, err == nil
+}
+
+func Sort(sortOpts types.Sort, data []map[string]interface{}) []map[string]interface{} {
+	sort.SliceStable(data, func(i, j int) bool {
+		return Less(sortOpts, data[i], data[j])
+	})
+	return data
+}
+
+// Paginate pages through sorted data. The marker is a continue token holding the
+// sort value and id of the first item of the page, so a page still starts at the
+// right place when that item has been removed in the meantime. A plain id is
+// accepted as marker too.
+func Paginate(sortOpts types.Sort, pagination *types.Pagination, data []map[string]interface{}) []map[string]interface{} {
+	if pagination == nil || pagination.Limit == nil {
+		return data
+	}
+
+	limit := *pagination.Limit
+	if limit < 0 {
+		limit = 0
+	}
+
+	total := int64(len(data))
+
+	pagination.Next = ""
+	pagination.Previous = ""
+	pagination.First = ""
+	pagination.Last = ""
+	pagination.Partial = false
+	pagination.Total = &total
+
+	if total == 0 {
+		return data
+	}
+
+	startIndex := int64(0)
+	if pagination.Marker != "" {
+		startIndex = markerIndex(sortOpts, pagination.Marker, data)
+	}
+
+	previousIndex := startIndex - limit
+	if previousIndex < 0 {
+		previousIndex = 0
+	}
+	nextIndex := startIndex + limit
+	if nextIndex > total {
+		nextIndex = total
+	}
+
+	if previousIndex < startIndex {
+		pagination.Previous = token(sortOpts, data[previousIndex])
+	}
+	if nextIndex > startIndex && nextIndex < total {
+		pagination.Next = token(sortOpts, data[nextIndex])
+	}
+
+	pagination.Partial = startIndex > 0 || nextIndex < total
+	if pagination.Partial {
+		pagination.First = token(sortOpts, data[0])
+		lastIndex := total - limit
+		if lastIndex > 0 {
+			pagination.Last = token(sortOpts, data[lastIndex])
+		}
+	}
+
+	return data[startIndex:nextIndex]
+}
+
+func sortField(sortOpts types.Sort) string {
+	if sortOpts.Name == "" {
+		return "id"
+	}
+	return sortOpts.Name
+}
+
+func token(sortOpts types.Sort, item map[string]interface{}) string {
+	bytes, err := json.Marshal([]string{
+		convert.ToString(item[sortField(sortOpts)]),
+		convert.ToString(item["id"]),
+	})
+	if err != nil {
+		return convert.ToString(item["id"])
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+func markerIndex(sortOpts types.Sort, marker string, data []map[string]interface{}) int64 {
+	var parts []string
+	bytes, err := base64.RawURLEncoding.DecodeString(marker)
+	if err != nil || json.Unmarshal(bytes, &parts) != nil || len(parts) != 2 {
+		for i, item := range data {
+			if convert.ToString(item["id"]) == marker {
+				return int64(i)
+			}
+		}
+		return 0
+	}
+
+	markerItem := map[string]interface{}{
+		sortField(sortOpts): parts[0],
+		"id":                parts[1],
+	}
+
+	return int64(sort.Search(len(data), func(i int) bool {
+		return !Less(sortOpts, data[i], markerItem)
+	}))
+}
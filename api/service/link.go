@@ -0,0 +1,123 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/rancher/cluster-api/api/pod"
+	"github.com/rancher/cluster-api/auth"
+	"github.com/rancher/cluster-api/k8s"
+	"github.com/rancher/norman/api/handler"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	"k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+type LinkHandler struct {
+	K8sClient rest.Interface
+	Pods      *pod.LinkHandler
+}
+
+func (l *LinkHandler) Formatter(apiContext *types.APIContext, resource *types.RawResource) {
+	namespace, _ := resource.Values["namespaceId"].(string)
+	if auth.Can(apiContext, auth.Permission{
+		Verb:        "create",
+		Resource:    "pods",
+		Subresource: "portforward",
+		Namespace:   namespace,
+	}) {
+		resource.Links["portforward"] = apiContext.URLBuilder.Link("portforward", resource)
+	}
+}
+
+func (l *LinkHandler) ListHandler(apiContext *types.APIContext) error {
+	switch apiContext.Link {
+	case "":
+		return handler.ListHandler(apiContext)
+	case "portforward":
+		return pod.WithResource(apiContext, l.portForward)
+	default:
+		return apiContext.Schema.LinkHandler(apiContext)
+	}
+}
+
+// portForward forwards service ports to the matching target ports of a ready pod
+// behind the service
+func (l *LinkHandler) portForward(apiContext *types.APIContext, namespace, name string) error {
+	var service v1.Service
+	if err := l.get(apiContext, namespace, "services", name, &service); err != nil {
+		return err
+	}
+
+	var endpoints v1.Endpoints
+	if err := l.get(apiContext, namespace, "endpoints", name, &endpoints); err != nil {
+		return err
+	}
+
+	portNames := map[int32]string{}
+	for _, port := range service.Spec.Ports {
+		portNames[port.Port] = port.Name
+	}
+
+	var names []string
+	for _, port := range apiContext.Request.URL.Query()["ports"] {
+		i, err := strconv.Atoi(port)
+		if err != nil {
+			return httperror.NewFieldAPIError(httperror.InvalidFormat, "ports", "invalid port "+port)
+		}
+		portName, ok := portNames[int32(i)]
+		if !ok {
+			return httperror.NewFieldAPIError(httperror.InvalidOption, "ports", "service has no port "+port)
+		}
+		names = append(names, portName)
+	}
+
+	if len(names) == 0 {
+		return httperror.NewFieldAPIError(httperror.MissingRequired, "ports", "")
+	}
+
+	for _, subset := range endpoints.Subsets {
+		targetPorts, ok := targetPorts(subset, names)
+		if !ok {
+			continue
+		}
+
+		for _, address := range subset.Addresses {
+			if address.TargetRef != nil && address.TargetRef.Kind == "Pod" {
+				return l.Pods.PortForward(apiContext, namespace, address.TargetRef.Name, targetPorts)
+			}
+		}
+	}
+
+	return httperror.NewAPIError(httperror.InvalidState, "service has no ready pods")
+}
+
+func targetPorts(subset v1.EndpointSubset, names []string) ([]string, bool) {
+	var result []string
+	for _, name := range names {
+		found := false
+		for _, port := range subset.Ports {
+			if port.Name == name {
+				result = append(result, strconv.Itoa(int(port.Port)))
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return result, true
+}
+
+func (l *LinkHandler) get(apiContext *types.APIContext, namespace, resource, name string, obj interface{}) error {
+	data, err := k8s.CallerRequest(apiContext, l.K8sClient, http.MethodGet, k8s.CoreV1, namespace, resource, name).
+		Do().
+		Raw()
+	if err != nil {
+		return k8s.TranslateError(err)
+	}
+	return json.Unmarshal(data, obj)
+}
@@ -0,0 +1,31 @@
+package setup
+
+import "github.com/rancher/cluster-api/auth"
+
+// Resources maps schema IDs to the kubernetes resources their stores use, for
+// access reviews. Secret subtypes are found through their secret base type.
+// Workloads with a type prefixed ID, like deployment:ns:name, are checked
+// against the resource of the type instead, workload is the one they are
+// created as.
+var Resources = map[string]auth.Resource{
+	"configMap":             {Group: "", Resource: "configmaps", Namespaced: true},
+	"cronJob":               {Group: "batch", Resource: "cronjobs", Namespaced: true},
+	"daemonSet":             {Group: "apps", Resource: "daemonsets", Namespaced: true},
+	"deployment":            {Group: "apps", Resource: "deployments", Namespaced: true},
+	"event":                 {Group: "", Resource: "events", Namespaced: true},
+	"dnsRecord":             {Group: "", Resource: "services", Namespaced: true},
+	"ingress":               {Group: "extensions", Resource: "ingresses", Namespaced: true},
+	"job":                   {Group: "batch", Resource: "jobs", Namespaced: true},
+	"namespace":             {Group: "", Resource: "namespaces"},
+	"namespacedSecret":      {Group: "", Resource: "secrets", Namespaced: true},
+	"node":                  {Group: "", Resource: "nodes"},
+	"persistentVolume":      {Group: "", Resource: "persistentvolumes"},
+	"persistentVolumeClaim": {Group: "", Resource: "persistentvolumeclaims", Namespaced: true},
+	"pod":                   {Group: "", Resource: "pods", Namespaced: true},
+	"replicaSet":            {Group: "apps", Resource: "replicasets", Namespaced: true},
+	"replicationController": {Group: "", Resource: "replicationcontrollers", Namespaced: true},
+	"secret":                {Group: "", Resource: "secrets", Namespaced: true},
+	"service":               {Group: "", Resource: "services", Namespaced: true},
+	"statefulSet":           {Group: "apps", Resource: "statefulsets", Namespaced: true},
+	"workload":              {Group: "project.cattle.io", Resource: "workloads", Namespaced: true},
+}
@@ -3,13 +3,17 @@ package setup
 import (
 	"context"
 
+	"github.com/rancher/cluster-api/api/configmap"
+	"github.com/rancher/cluster-api/api/event"
+	"github.com/rancher/cluster-api/api/node"
 	"github.com/rancher/cluster-api/api/pod"
+	"github.com/rancher/cluster-api/api/service"
+	"github.com/rancher/cluster-api/api/subscribe"
 	"github.com/rancher/cluster-api/api/workload"
 	"github.com/rancher/cluster-api/store/ingress"
 	"github.com/rancher/cluster-api/store/secret"
-	"github.com/rancher/norman/pkg/subscribe"
+	"github.com/rancher/cluster-api/store/selector"
 	"github.com/rancher/norman/store/crd"
-	"github.com/rancher/norman/store/proxy"
 	"github.com/rancher/norman/store/subtype"
 	"github.com/rancher/norman/store/transform"
 	"github.com/rancher/norman/types"
@@ -21,20 +25,31 @@ import (
 )
 
 func Schemas(ctx context.Context, app *config.ClusterContext, schemas *types.Schemas) error {
-	subscribe.Register(&clusterSchema.Version, schemas)
-	subscribe.Register(&schema.Version, schemas)
+	subscribe.Register(ctx, &clusterSchema.Version, schemas)
+	subscribe.Register(ctx, &schema.Version, schemas)
+
+	owners, err := workload.NewOwnerCache(ctx, app.K8sClient)
+	if err != nil {
+		return err
+	}
+
+	workload.AddBatchSchemas(schemas)
+	event.AddSchemas(schemas)
+	ConfigMap(app.UnversionedClient, schemas)
+	CronJob(app.UnversionedClient, schemas)
 	DaemonSet(app.UnversionedClient, schemas)
 	Deployment(app.UnversionedClient, schemas)
 	Ingress(app.WorkloadContext(), schemas)
+	Job(app.UnversionedClient, schemas)
 	Namespace(app.UnversionedClient, schemas)
-	Node(app.UnversionedClient, schemas)
+	Node(ctx, app.UnversionedClient, schemas)
 	PersistentVolume(app.UnversionedClient, schemas)
 	PersistentVolumeClaims(app.UnversionedClient, schemas)
-	Pod(app.UnversionedClient, schemas)
+	podLinks := Pod(app.UnversionedClient, &app.RESTConfig, schemas, owners)
 	ReplicaSet(app.UnversionedClient, schemas)
 	ReplicationController(app.UnversionedClient, schemas)
 	Secret(app.UnversionedClient, schemas)
-	Service(app.UnversionedClient, schemas)
+	Service(app.UnversionedClient, schemas, podLinks)
 	StatefulSet(app.UnversionedClient, schemas)
 
 	crdStore, err := crd.NewCRDStoreFromConfig(app.RESTConfig)
@@ -42,25 +57,62 @@ func Schemas(ctx context.Context, app *config.ClusterContext, schemas *types.Sch
 		return err
 	}
 
-	if err := crdStore.AddSchemas(ctx, schemas.Schema(&schema.Version, client.WorkloadType)); err != nil {
+	workloadSchema := schemas.Schema(&schema.Version, client.WorkloadType)
+	if err := crdStore.AddSchemas(ctx, workloadSchema); err != nil {
 		return err
 	}
+	// The CRD store can't resume watches, so it only does the rest
+	workloadSchema.Store = selector.WrapStore(workloadSchema.Store, app.UnversionedClient,
+		[]string{"apis"},
+		"project.cattle.io",
+		"v3",
+		"workloads",
+		nil)
 
 	// After CRD store is set on workload
-	Workload(schemas)
+	Workload(app.UnversionedClient, schemas, owners)
+	// Last, the events link goes in front of the other links
+	Event(app.UnversionedClient, schemas)
 
 	return nil
 }
 
+func Event(k8sClient rest.Interface, schemas *types.Schemas) {
+	store := selector.NewProxyStore(k8sClient,
+		[]string{"api"},
+		"",
+		"v1",
+		"Event",
+		"events",
+		event.Fields)
+	schemas.Schema(&schema.Version, event.EventType).Store = store
+	schemas.Schema(&clusterSchema.Version, event.EventType).Store = store
+
+	event.AddLink(
+		schemas.Schema(&clusterSchema.Version, "node"),
+		schemas.Schema(&schema.Version, "cronJob"),
+		schemas.Schema(&schema.Version, "daemonSet"),
+		schemas.Schema(&schema.Version, "deployment"),
+		schemas.Schema(&schema.Version, "ingress"),
+		schemas.Schema(&schema.Version, "job"),
+		schemas.Schema(&schema.Version, "persistentVolumeClaim"),
+		schemas.Schema(&schema.Version, client.PodType),
+		schemas.Schema(&schema.Version, "replicaSet"),
+		schemas.Schema(&schema.Version, "replicationController"),
+		schemas.Schema(&schema.Version, "statefulSet"),
+		schemas.Schema(&schema.Version, client.WorkloadType))
+}
+
 func Namespace(k8sClient rest.Interface, schemas *types.Schemas) {
 	schema := schemas.Schema(&schema.Version, "namespace")
 	schema.Store = &transform.Store{
-		Store: proxy.NewProxyStore(k8sClient,
+		Store: selector.NewProxyStore(k8sClient,
 			[]string{"api"},
 			"",
 			"v1",
 			"Namespace",
-			"namespaces"),
+			"namespaces",
+			nil),
 		Transformer: func(apiContext *types.APIContext, data map[string]interface{}) (map[string]interface{}, error) {
 			anns, _ := data["annotations"].(map[string]interface{})
 			if anns["management.cattle.io/system-namespace"] == "true" {
@@ -74,111 +126,170 @@ func Namespace(k8sClient rest.Interface, schemas *types.Schemas) {
 	clusterSchema.Store = schema.Store
 }
 
-func Node(k8sClient rest.Interface, schemas *types.Schemas) {
+func Node(ctx context.Context, k8sClient rest.Interface, schemas *types.Schemas) {
 	schema := schemas.Schema(&clusterSchema.Version, "node")
-	schema.Store = proxy.NewProxyStore(k8sClient,
-		[]string{"api"},
-		"",
-		"v1",
-		"Node",
-		"nodes")
+	schema.Store = &transform.Store{
+		Store: selector.NewProxyStore(k8sClient,
+			[]string{"api"},
+			"",
+			"v1",
+			"Node",
+			"nodes",
+			nil),
+		Transformer: node.Transform,
+	}
+	node.ConfigureActions(ctx, k8sClient, schemas)
 }
 
 func PersistentVolume(k8sClient rest.Interface, schemas *types.Schemas) {
 	schema := schemas.Schema(&clusterSchema.Version, "persistentVolume")
-	schema.Store = proxy.NewProxyStore(k8sClient,
+	schema.Store = selector.NewProxyStore(k8sClient,
 		[]string{"api"},
 		"",
 		"v1",
 		"PersistentVolume",
-		"persistentvolumes")
+		"persistentvolumes",
+		nil)
 }
 
 func PersistentVolumeClaims(k8sClient rest.Interface, schemas *types.Schemas) {
 	schema := schemas.Schema(&schema.Version, "persistentVolumeClaim")
-	schema.Store = proxy.NewProxyStore(k8sClient,
+	schema.Store = selector.NewProxyStore(k8sClient,
 		[]string{"api"},
 		"",
 		"v1",
 		"PersistentVolumeClaim",
-		"persistentvolumeclaims")
+		"persistentvolumeclaims",
+		nil)
+}
+
+func ConfigMap(k8sClient rest.Interface, schemas *types.Schemas) {
+	schema := configmap.ConfigureSchema(schemas)
+	schema.Store = configmap.NewConfigMapStore(k8sClient)
 }
 
 func DaemonSet(k8sClient rest.Interface, schemas *types.Schemas) {
 	schema := schemas.Schema(&schema.Version, "daemonSet")
 	schema.Store = &workload.PrefixTypeStore{
-		Store: proxy.NewProxyStore(k8sClient,
+		Store: selector.NewProxyStore(k8sClient,
 			[]string{"apis"},
 			"apps",
 			"v1beta2",
 			"DaemonSet",
-			"daemonsets"),
+			"daemonsets",
+			nil),
 	}
 }
 
 func ReplicaSet(k8sClient rest.Interface, schemas *types.Schemas) {
 	schema := schemas.Schema(&schema.Version, "replicaSet")
 	schema.Store = &workload.PrefixTypeStore{
-		Store: proxy.NewProxyStore(k8sClient,
+		Store: selector.NewProxyStore(k8sClient,
 			[]string{"apis"},
 			"apps",
 			"v1beta2",
 			"ReplicaSet",
-			"replicasets"),
+			"replicasets",
+			nil),
 	}
 }
 
 func ReplicationController(k8sClient rest.Interface, schemas *types.Schemas) {
 	schema := schemas.Schema(&schema.Version, "replicationController")
 	schema.Store = &workload.PrefixTypeStore{
-		Store: proxy.NewProxyStore(k8sClient,
+		Store: selector.NewProxyStore(k8sClient,
 			[]string{"api"},
 			"",
 			"v1",
 			"ReplicationController",
-			"replicationcontrollers"),
+			"replicationcontrollers",
+			nil),
+	}
+}
+
+func Job(k8sClient rest.Interface, schemas *types.Schemas) {
+	schema := schemas.Schema(&schema.Version, workload.JobType)
+	schema.Store = &workload.PrefixTypeStore{
+		Store: selector.NewProxyStore(k8sClient,
+			[]string{"apis"},
+			"batch",
+			"v1",
+			"Job",
+			"jobs",
+			nil),
+	}
+}
+
+func CronJob(k8sClient rest.Interface, schemas *types.Schemas) {
+	schema := schemas.Schema(&schema.Version, workload.CronJobType)
+	schema.Store = &workload.PrefixTypeStore{
+		Store: selector.NewProxyStore(k8sClient,
+			[]string{"apis"},
+			"batch",
+			"v1beta1",
+			"CronJob",
+			"cronjobs",
+			nil),
 	}
 }
 
 func Deployment(k8sClient rest.Interface, schemas *types.Schemas) {
 	schema := schemas.Schema(&schema.Version, "deployment")
 	schema.Store = &workload.PrefixTypeStore{
-		Store: proxy.NewProxyStore(k8sClient,
+		Store: selector.NewProxyStore(k8sClient,
 			[]string{"apis"},
 			"apps",
 			"v1beta2",
 			"Deployment",
-			"deployments"),
+			"deployments",
+			nil),
 	}
 }
 
-func Workload(schemas *types.Schemas) {
-	workload.ConfigureStore(schemas)
+func Workload(k8sClient rest.Interface, schemas *types.Schemas, owners *workload.OwnerCache) {
+	workload.ConfigureStore(schemas, &workload.StateTransformer{
+		Owners: owners,
+	})
+	workload.ConfigureActions(k8sClient, schemas)
+
+	workloadSchema := schemas.Schema(&schema.Version, client.WorkloadType)
+	workloadSchema.ListHandler = workload.ListHandler(workloadSchema.ListHandler)
 }
 
 func StatefulSet(k8sClient rest.Interface, schemas *types.Schemas) {
 	schema := schemas.Schema(&schema.Version, "statefulSet")
 	schema.Store = &workload.PrefixTypeStore{
-		Store: proxy.NewProxyStore(k8sClient,
+		Store: selector.NewProxyStore(k8sClient,
 			[]string{"apis"},
 			"apps",
 			"v1beta2",
 			"StatefulSet",
-			"statefulsets"),
+			"statefulsets",
+			nil),
 	}
 }
 
-func Service(k8sClient rest.Interface, schemas *types.Schemas) {
+func Service(k8sClient rest.Interface, schemas *types.Schemas, podLinks *pod.LinkHandler) {
+	linkHandler := &service.LinkHandler{
+		K8sClient: k8sClient,
+		Pods:      podLinks,
+	}
+
 	schema := schemas.Schema(&schema.Version, "dnsRecord")
-	schema.Store = proxy.NewProxyStore(k8sClient,
+	schema.Store = selector.NewProxyStore(k8sClient,
 		[]string{"api"},
 		"",
 		"v1",
 		"Service",
-		"services")
+		"services",
+		nil)
+	schema.Formatter = linkHandler.Formatter
+	schema.ListHandler = linkHandler.ListHandler
 
 	serviceSchema := schemas.Schema(&schema.Version, "service")
 	serviceSchema.Store = schema.Store
+	serviceSchema.Formatter = linkHandler.Formatter
+	serviceSchema.ListHandler = linkHandler.ListHandler
 }
 
 func Ingress(workload *config.WorkloadContext, schemas *types.Schemas) {
@@ -197,17 +308,33 @@ func Secret(k8sClient rest.Interface, schemas *types.Schemas) {
 	}
 }
 
-func Pod(k8sClient rest.Interface, schemas *types.Schemas) {
+func Pod(k8sClient rest.Interface, restConfig *rest.Config, schemas *types.Schemas, owners *workload.OwnerCache) *pod.LinkHandler {
+	transformer := &pod.Transformer{
+		Owners: owners,
+	}
+	linkHandler := &pod.LinkHandler{
+		K8sClient: k8sClient,
+		Config:    restConfig,
+	}
+
+	pod.ConfigureSchema(schemas)
 	schema := schemas.Schema(&schema.Version, client.PodType)
 	schema.Store = &transform.Store{
-		Store: proxy.NewProxyStore(k8sClient,
+		Store: selector.NewProxyStore(k8sClient,
 			[]string{"api"},
 			"",
 			"v1",
 			"Pod",
-			"pods"),
-		Transformer:       pod.Transform,
-		ListTransformer:   pod.ListTransform,
-		StreamTransformer: pod.StreamTransform,
+			"pods",
+			map[string]string{
+				client.PodFieldNodeId: "spec.nodeName",
+			}),
+		Transformer:       transformer.Transform,
+		ListTransformer:   transformer.ListTransform,
+		StreamTransformer: transformer.StreamTransform,
 	}
+	schema.Formatter = linkHandler.Formatter
+	schema.ListHandler = linkHandler.ListHandler
+
+	return linkHandler
 }
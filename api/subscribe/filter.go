@@ -0,0 +1,97 @@
+package subscribe
+
+import (
+	"github.com/rancher/cluster-api/store/selector"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/types/client/project/v3"
+)
+
+// filter narrows a subscription down to a namespace, a project or a label
+// selector. Each is passed on to the stores, so the watches themselves are
+// narrower, and checked again on every event.
+type filter struct {
+	namespaceID string
+	projectID   string
+}
+
+func parseFilter(apiContext *types.APIContext) (*filter, error) {
+	// The stores read the selector from the request, it only needs to be valid
+	if _, err := selector.LabelSelector(apiContext); err != nil {
+		return nil, err
+	}
+
+	query := apiContext.Request.URL.Query()
+	f := &filter{
+		namespaceID: query.Get(client.PodFieldNamespaceId),
+		projectID:   query.Get(client.PodFieldProjectID),
+	}
+
+	if projectID, ok := apiContext.SubContext["projects"]; ok {
+		if f.projectID != "" && f.projectID != projectID {
+			return nil, httperror.NewFieldAPIError(httperror.InvalidOption, client.PodFieldProjectID,
+				"subscription is for project "+projectID)
+		}
+		f.projectID = projectID
+	}
+	if ns, ok := apiContext.SubContext["namespaces"]; ok {
+		if f.namespaceID != "" && f.namespaceID != ns {
+			return nil, httperror.NewFieldAPIError(httperror.InvalidOption, client.PodFieldNamespaceId,
+				"subscription is for namespace "+ns)
+		}
+		f.namespaceID = ns
+	}
+
+	return f, nil
+}
+
+// matches is false for types the filter can't apply to, like nodes when
+// filtering on a namespace
+func (f *filter) matches(schema *types.Schema) bool {
+	if f.namespaceID != "" && !hasField(schema, client.PodFieldNamespaceId) {
+		return false
+	}
+	if f.projectID != "" && !hasField(schema, client.PodFieldProjectID) {
+		return false
+	}
+	return true
+}
+
+// apply scopes a copy of apiContext to the project and adds the conditions to opts
+func (f *filter) apply(apiContext *types.APIContext, schema *types.Schema, opts *types.QueryOptions) {
+	if f.projectID != "" && hasField(schema, client.PodFieldNamespaceId) {
+		subContext := map[string]string{}
+		for k, v := range apiContext.SubContext {
+			subContext[k] = v
+		}
+		subContext["projects"] = f.projectID
+		apiContext.SubContext = subContext
+	}
+
+	// Passed on in front, so the stores see them before anything the client
+	// sent on its own
+	var conditions []*types.QueryCondition
+	if f.namespaceID != "" {
+		conditions = append(conditions, types.NewConditionFromString(client.PodFieldNamespaceId, types.ModifierEQ, f.namespaceID))
+	}
+	if f.projectID != "" {
+		conditions = append(conditions, types.NewConditionFromString(client.PodFieldProjectID, types.ModifierEQ, f.projectID))
+	}
+	opts.Conditions = append(conditions, opts.Conditions...)
+}
+
+// valid checks an event against the conditions, the stores only use the ones
+// they can pass on to the apiserver
+func valid(conditions []*types.QueryCondition, item map[string]interface{}) bool {
+	for _, condition := range conditions {
+		if !condition.Valid(item) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasField(schema *types.Schema, name string) bool {
+	_, ok := schema.ResourceFields[name]
+	return ok
+}
@@ -0,0 +1,231 @@
+package subscribe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/norman/api/writer"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/norman/types/slice"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	active sync.WaitGroup
+
+	// closing is closed once the server shuts down
+	closing   = make(chan struct{})
+	closeOnce sync.Once
+)
+
+// conn is how events get to the client, a websocket or an event stream
+type conn interface {
+	// Write sends the JSON message of the named event
+	Write(name string, message []byte) error
+	// GoingAway tells the client the server is shutting down
+	GoingAway() error
+	Close() error
+}
+
+// Close tells the open subscriptions to go away and refuses new ones. It is run
+// as the server shuts down, subscriptions would otherwise keep it waiting.
+func Close() {
+	closeOnce.Do(func() {
+		close(closing)
+	})
+}
+
+// Drain waits for all open subscriptions to finish, or for ctx to be done
+func Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type handler struct {
+	ctx context.Context
+}
+
+func (h *handler) Handler(apiContext *types.APIContext) error {
+	active.Add(1)
+	defer active.Done()
+
+	err := h.subscribe(apiContext)
+	if err != nil {
+		logrus.Errorf("Error during subscribe %v", err)
+	}
+	return err
+}
+
+func getMatchingSchemas(apiContext *types.APIContext, f *filter) []*types.Schema {
+	apiVersions := apiContext.Request.URL.Query()["apiVersions"]
+	resourceTypes := apiContext.Request.URL.Query()["resourceTypes"]
+
+	var schemas []*types.Schema
+	for _, schema := range apiContext.Schemas.Schemas() {
+		if !matches(apiVersions, schema.Version.Path) {
+			continue
+		}
+		if !matches(resourceTypes, schema.ID) {
+			continue
+		}
+		if !f.matches(schema) {
+			continue
+		}
+		if schema.Store != nil {
+			schemas = append(schemas, schema)
+		}
+	}
+
+	return schemas
+}
+
+func (h *handler) subscribe(apiContext *types.APIContext) error {
+	select {
+	case <-closing:
+		return httperror.NewAPIError(httperror.ServerError, "server is shutting down")
+	case <-h.ctx.Done():
+		return httperror.NewAPIError(httperror.ServerError, "server is shutting down")
+	default:
+	}
+
+	f, err := parseFilter(apiContext)
+	if err != nil {
+		return err
+	}
+
+	schemas := getMatchingSchemas(apiContext, f)
+	if len(schemas) == 0 {
+		return httperror.NewAPIError(httperror.NotFound, "no resources types matched")
+	}
+
+	cancelCtx, cancel := context.WithCancel(apiContext.Request.Context())
+	defer cancel()
+
+	var c conn
+	if isEventStream(apiContext.Request) {
+		c, err = newSSEConn(apiContext)
+	} else {
+		c, err = newWebsocketConn(apiContext, cancel)
+	}
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	readerGroup, ctx := errgroup.WithContext(cancelCtx)
+	apiContext.Request = apiContext.Request.WithContext(ctx)
+
+	versions := resourceVersions(apiContext)
+	events := make(chan event)
+	for _, schema := range schemas {
+		s := &stream{
+			apiContext:      apiContext,
+			schema:          schema,
+			resourceVersion: versions[schema.ID],
+			filter:          f,
+			result:          events,
+		}
+		readerGroup.Go(func() error {
+			return s.run(ctx)
+		})
+	}
+
+	go func() {
+		readerGroup.Wait()
+		close(events)
+	}()
+
+	jsonWriter := writer.JSONResponseWriter{}
+	t := time.NewTicker(5 * time.Second)
+	defer t.Stop()
+
+	done := false
+	for !done {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				done = true
+				break
+			}
+
+			schema := e.schema
+			if item, ok := e.data.(map[string]interface{}); ok {
+				schema = apiContext.Schemas.Schema(apiContext.Version, convert.ToString(item["type"]))
+			}
+			if schema != nil {
+				buffer := &bytes.Buffer{}
+				if err := jsonWriter.VersionBody(apiContext, &schema.Version, buffer, e.data); err != nil {
+					return err
+				}
+
+				if err := c.Write(e.name, message(eventHeader(e, schema), buffer.Bytes())); err != nil {
+					return err
+				}
+			}
+		case <-t.C:
+			if err := c.Write(pingEvent, message(`{"name":"ping","data":`, []byte("{}"))); err != nil {
+				return err
+			}
+		case <-closing:
+			// Stop the watches and tell the client to reconnect somewhere else
+			cancel()
+			return c.GoingAway()
+		case <-h.ctx.Done():
+			cancel()
+			return c.GoingAway()
+		}
+	}
+
+	// Group is already done at this point because of goroutine above, this is just to send the error if needed
+	return readerGroup.Wait()
+}
+
+// eventHeader starts the message of an event. resourceVersion is what a client
+// passes back in resourceVersions to resume, resourceType names the type a
+// resync replaces.
+func eventHeader(e event, schema *types.Schema) string {
+	header := map[string]string{
+		"name": e.name,
+	}
+	if e.resourceVersion != "" {
+		header["resourceVersion"] = e.resourceVersion
+	}
+	if e.name == resyncEvent {
+		header["resourceType"] = schema.ID
+	}
+
+	buf, _ := json.Marshal(header)
+	return strings.TrimSuffix(string(buf), "}") + `,"data":`
+}
+
+// message completes an event whose header leaves off at "data":
+func message(header string, data []byte) []byte {
+	buffer := bytes.NewBufferString(header)
+	buffer.Write(bytes.TrimSpace(data))
+	buffer.WriteString("}")
+	return buffer.Bytes()
+}
+
+func matches(items []string, item string) bool {
+	if len(items) == 0 {
+		return true
+	}
+	return slice.ContainsString(items, item)
+}
@@ -0,0 +1,32 @@
+package subscribe
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rancher/norman/types"
+)
+
+type Subscribe struct {
+	ResourceTypes    []string
+	APIVersions      []string
+	ProjectID        string `norman:"type=reference[project]"`
+	ResourceVersions []string
+	NamespaceID      string `norman:"type=reference[namespace]"`
+	LabelSelector    string
+}
+
+// Register adds the subscribe collection to the version. Subscriptions are closed
+// with a close frame when ctx is done.
+func Register(ctx context.Context, version *types.APIVersion, schemas *types.Schemas) {
+	h := &handler{
+		ctx: ctx,
+	}
+
+	schemas.MustImportAndCustomize(version, Subscribe{}, func(schema *types.Schema) {
+		schema.CollectionMethods = []string{http.MethodGet}
+		schema.ResourceMethods = []string{}
+		schema.ListHandler = h.Handler
+		schema.PluralName = "subscribe"
+	})
+}
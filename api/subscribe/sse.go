@@ -0,0 +1,71 @@
+package subscribe
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+)
+
+const eventStreamType = "text/event-stream"
+
+// isEventStream is true when the client asks for server-sent events, for those
+// behind proxies that don't pass websockets
+func isEventStream(req *http.Request) bool {
+	for _, accept := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == eventStreamType {
+			return true
+		}
+	}
+	return false
+}
+
+// sseConn writes each event as a server-sent event named like the event, with
+// the same message a websocket gets as data. The client going away ends the
+// request context.
+type sseConn struct {
+	writer  http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newSSEConn(apiContext *types.APIContext) (*sseConn, error) {
+	flusher, ok := apiContext.Response.(http.Flusher)
+	if !ok {
+		return nil, httperror.NewAPIError(httperror.ServerError, "streaming is not supported")
+	}
+
+	header := apiContext.Response.Header()
+	header.Set("Content-Type", eventStreamType)
+	header.Set("Cache-Control", "no-cache")
+	// Keep nginx from buffering the stream
+	header.Set("X-Accel-Buffering", "no")
+	apiContext.Response.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &sseConn{
+		writer:  apiContext.Response,
+		flusher: flusher,
+	}, nil
+}
+
+func (s *sseConn) Write(name string, message []byte) error {
+	if _, err := fmt.Fprintf(s.writer, "event: %s\ndata: %s\n\n", name, message); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// GoingAway ends the stream. EventSource clients reconnect on their own, and
+// will be sent somewhere else.
+func (s *sseConn) GoingAway() error {
+	return nil
+}
+
+func (s *sseConn) Close() error {
+	return nil
+}
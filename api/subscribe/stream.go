@@ -0,0 +1,235 @@
+package subscribe
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rancher/cluster-api/store/selector"
+	"github.com/rancher/norman/parse"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	changeEvent = "resource.change"
+	removeEvent = "resource.remove"
+	resyncEvent = "resource.resync"
+	pingEvent   = "ping"
+
+	rewatchDelay = time.Second
+)
+
+var errNoWatch = errors.New("store does not support watch")
+
+type event struct {
+	name            string
+	resourceVersion string
+	// schema is only set for resync events, the others carry their type
+	schema *types.Schema
+	data   interface{}
+}
+
+// stream follows one schema for a subscription. The apiserver ends watches
+// routinely, so the stream watches again from the last resourceVersion it sent,
+// and relists when that version has expired or it doesn't have one yet.
+type stream struct {
+	apiContext      *types.APIContext
+	schema          *types.Schema
+	resourceVersion string
+	filter          *filter
+	result          chan<- event
+}
+
+func (s *stream) run(ctx context.Context) error {
+	for {
+		// A watch without a version starts over with every object as a change,
+		// and misses what was removed since the last one
+		if s.resourceVersion == "" {
+			if err := s.resync(ctx); err != nil || ctx.Err() != nil {
+				return err
+			}
+		}
+
+		gone, err := s.watch(ctx)
+		if err == errNoWatch {
+			return nil
+		}
+		if err != nil || ctx.Err() != nil {
+			return err
+		}
+
+		if gone {
+			s.resourceVersion = ""
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(rewatchDelay):
+		}
+	}
+}
+
+// watch sends events until the watch ends and returns whether it ended because
+// the resourceVersion has expired
+func (s *stream) watch(ctx context.Context) (bool, error) {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var gone int32
+	watchCtx = selector.WithWatchErrorHandler(watchCtx, func(err error) {
+		if apierrors.IsGone(err) || apierrors.IsResourceExpired(err) {
+			atomic.StoreInt32(&gone, 1)
+		}
+		// Stop any other watches this one is merged with, so the schema is
+		// watched again as a whole
+		cancel()
+	})
+
+	apiContext := *s.apiContext
+	apiContext.Request = s.apiContext.Request.WithContext(watchCtx)
+
+	opts := parse.QueryOptions(&apiContext, s.schema)
+	opts.Options = map[string]string{
+		selector.ResourceVersionOption: s.resourceVersion,
+	}
+	s.filter.apply(&apiContext, s.schema, &opts)
+
+	events, err := s.schema.Store.Watch(&apiContext, s.schema, &opts)
+	if err != nil {
+		return false, err
+	}
+	if events == nil {
+		return false, errNoWatch
+	}
+
+	for item := range events {
+		if !valid(opts.Conditions, item) {
+			continue
+		}
+
+		e := event{
+			name:            changeEvent,
+			resourceVersion: convert.ToString(item[selector.ResourceVersionField]),
+			data:            item,
+		}
+		if item[".removed"] == true {
+			e.name = removeEvent
+		}
+
+		select {
+		case s.result <- e:
+			s.resourceVersion = newerVersion(s.resourceVersion, e.resourceVersion)
+		case <-ctx.Done():
+			// Let the store finish writing so it can shut down
+			go func() {
+				for range events {
+				}
+			}()
+			return false, nil
+		}
+	}
+
+	return atomic.LoadInt32(&gone) == 1, nil
+}
+
+// resync lists the schema again and sends the whole list, which replaces
+// everything the client has of the type. The watch picks up from the version of
+// the list, or from the oldest one when the list is made of several.
+func (s *stream) resync(ctx context.Context) error {
+	var (
+		lock    sync.Mutex
+		version string
+	)
+	listCtx := selector.WithListVersionHandler(ctx, func(listVersion string) {
+		lock.Lock()
+		defer lock.Unlock()
+		version = olderVersion(version, listVersion)
+	})
+
+	apiContext := *s.apiContext
+	apiContext.Request = s.apiContext.Request.WithContext(listCtx)
+	opts := parse.QueryOptions(&apiContext, s.schema)
+	opts.Pagination = nil
+	s.filter.apply(&apiContext, s.schema, &opts)
+
+	data, err := s.schema.Store.List(&apiContext, s.schema, &opts)
+	if err != nil {
+		return err
+	}
+
+	matched := []map[string]interface{}{}
+	for _, item := range data {
+		if valid(opts.Conditions, item) {
+			matched = append(matched, item)
+		}
+	}
+
+	lock.Lock()
+	s.resourceVersion = version
+	lock.Unlock()
+
+	select {
+	case s.result <- event{
+		name:            resyncEvent,
+		resourceVersion: s.resourceVersion,
+		schema:          s.schema,
+		data:            matched,
+	}:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+// newerVersion compares resourceVersions as the etcd revisions they are. The
+// order of events from merged watches isn't strictly increasing.
+func newerVersion(current, version string) string {
+	if current == "" {
+		return version
+	}
+	c, err := strconv.ParseUint(current, 10, 64)
+	if err != nil {
+		return version
+	}
+	v, err := strconv.ParseUint(version, 10, 64)
+	if err != nil || v < c {
+		return current
+	}
+	return version
+}
+
+// olderVersion is the counterpart of newerVersion
+func olderVersion(current, version string) string {
+	if current == "" {
+		return version
+	}
+	c, err := strconv.ParseUint(current, 10, 64)
+	if err != nil {
+		return version
+	}
+	v, err := strconv.ParseUint(version, 10, 64)
+	if err != nil || v > c {
+		return current
+	}
+	return version
+}
+
+// resourceVersions reads the resourceVersions=<type>:<resourceVersion> parameters
+// a client sends to resume a subscription
+func resourceVersions(apiContext *types.APIContext) map[string]string {
+	result := map[string]string{}
+	for _, value := range apiContext.Request.URL.Query()["resourceVersions"] {
+		parts := strings.SplitN(value, ":", 2)
+		if len(parts) == 2 && parts[1] != "" {
+			result[parts[0]] = parts[1]
+		}
+	}
+	return result
+}
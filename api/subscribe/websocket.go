@@ -0,0 +1,57 @@
+package subscribe
+
+import (
+	"context"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/cluster-api/auth"
+	"github.com/rancher/norman/types"
+)
+
+const closeTimeout = 5 * time.Second
+
+var upgrader = websocket.Upgrader{}
+
+type websocketConn struct {
+	*websocket.Conn
+}
+
+// newWebsocketConn upgrades the request and calls cancel once the client goes away
+func newWebsocketConn(apiContext *types.APIContext, cancel context.CancelFunc) (*websocketConn, error) {
+	c, err := upgrader.Upgrade(apiContext.Response, apiContext.Request, auth.WebsocketResponseHeader(apiContext.Request))
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			if _, _, err := c.NextReader(); err != nil {
+				cancel()
+				c.Close()
+				break
+			}
+		}
+	}()
+
+	return &websocketConn{
+		Conn: c,
+	}, nil
+}
+
+func (w *websocketConn) Write(name string, message []byte) error {
+	messageWriter, err := w.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+
+	if _, err := messageWriter.Write(message); err != nil {
+		return err
+	}
+	return messageWriter.Close()
+}
+
+func (w *websocketConn) GoingAway() error {
+	message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	return w.WriteControl(websocket.CloseMessage, message, time.Now().Add(closeTimeout))
+}
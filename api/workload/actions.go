@@ -0,0 +1,385 @@
+package workload
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rancher/cluster-api/auth"
+	"github.com/rancher/cluster-api/k8s"
+	"github.com/rancher/norman/api/access"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/parse"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/norman/types/values"
+	"github.com/rancher/types/apis/project.cattle.io/v3/schema"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	patchtype "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	ScaleAction    = "scale"
+	PauseAction    = "pause"
+	ResumeAction   = "resume"
+	RedeployAction = "redeploy"
+	RollbackAction = "rollback"
+
+	// redeployAnnotation is set on the pod template, so changing it rolls out new pods
+	redeployAnnotation = "cattle.io/timestamp"
+)
+
+type ScaleInput struct {
+	Replicas int64 `json:"replicas,omitempty" norman:"required,min=0"`
+}
+
+type RollbackInput struct {
+	// Revision to roll back to, the one before the current if left out
+	Revision int64 `json:"revision,omitempty" norman:"min=0"`
+}
+
+// kind is a workload type the actions can be run on, keyed by the type prefix of
+// workload IDs
+type kind struct {
+	prefix   []string
+	group    string
+	resource string
+	actions  []string
+}
+
+var kinds = map[string]kind{
+	"deployment": {
+		prefix:   []string{"apis", "apps", "v1beta2"},
+		group:    "apps",
+		resource: "deployments",
+		actions:  []string{ScaleAction, PauseAction, ResumeAction, RedeployAction, RollbackAction},
+	},
+	"statefulset": {
+		prefix:   []string{"apis", "apps", "v1beta2"},
+		group:    "apps",
+		resource: "statefulsets",
+		actions:  []string{ScaleAction, RedeployAction, RollbackAction},
+	},
+	"daemonset": {
+		prefix:   []string{"apis", "apps", "v1beta2"},
+		group:    "apps",
+		resource: "daemonsets",
+		actions:  []string{RedeployAction, RollbackAction},
+	},
+	"replicaset": {
+		prefix:   []string{"apis", "apps", "v1beta2"},
+		group:    "apps",
+		resource: "replicasets",
+		actions:  []string{ScaleAction},
+	},
+	"replicationcontroller": {
+		prefix:   []string{"api", "v1"},
+		resource: "replicationcontrollers",
+		actions:  []string{ScaleAction},
+	},
+}
+
+// controllerRevisions keep the history of daemon sets and stateful sets
+var controllerRevisions = kind{
+	prefix:   []string{"apis", "apps", "v1beta2"},
+	group:    "apps",
+	resource: "controllerrevisions",
+}
+
+func (k kind) supports(action string) bool {
+	for _, a := range k.actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// permission is what the caller needs to run action
+func (k kind) permission(action, namespace string) auth.Permission {
+	if action == ScaleAction {
+		return auth.Permission{Verb: "update", Group: k.group, Resource: k.resource, Subresource: "scale", Namespace: namespace}
+	}
+	return auth.Permission{Verb: "patch", Group: k.group, Resource: k.resource, Namespace: namespace}
+}
+
+// historyPermission is what the caller needs to see the revisions
+func (k kind) historyPermission(namespace string) auth.Permission {
+	history := controllerRevisions
+	if k.resource == "deployments" {
+		history = kinds["replicaset"]
+	}
+	return auth.Permission{Verb: "list", Group: history.group, Resource: history.resource, Namespace: namespace}
+}
+
+// request starts a request made with the caller's identity, name and namespace
+// are left out when empty
+func (k kind) request(apiContext *types.APIContext, k8sClient rest.Interface, method, namespace, name string) *rest.Request {
+	return k8s.CallerRequest(apiContext, k8sClient, method, k.prefix, namespace, k.resource, name)
+}
+
+// ConfigureActions adds the workload actions to the workload types and to the
+// aggregate workload type, which runs them on the type in the ID.
+func ConfigureActions(k8sClient rest.Interface, schemas *types.Schemas) {
+	schemas.MustImport(&schema.Version, ScaleInput{})
+	schemas.MustImport(&schema.Version, RollbackInput{})
+	linkOnly := func(schema *types.Schema) {
+		schema.CollectionMethods = []string{}
+		schema.ResourceMethods = []string{}
+	}
+	schemas.MustImportAndCustomize(&schema.Version, WorkloadRevision{}, linkOnly)
+	schemas.MustImportAndCustomize(&schema.Version, RevisionDiff{}, linkOnly)
+
+	h := &ActionHandler{
+		K8sClient: k8sClient,
+	}
+
+	all := map[string]bool{}
+	for typeName, k := range kinds {
+		actionSchema := schemas.Schema(&schema.Version, typeName)
+		if actionSchema == nil {
+			continue
+		}
+		for _, action := range k.actions {
+			all[action] = true
+		}
+		h.configure(actionSchema, k.actions...)
+		if k.supports(RollbackAction) {
+			actionSchema.ListHandler = h.ListHandler
+		}
+	}
+
+	var actions []string
+	for action := range all {
+		actions = append(actions, action)
+	}
+	workloadSchema := schemas.Schema(&schema.Version, "workload")
+	h.configure(workloadSchema, actions...)
+	workloadSchema.ListHandler = h.ListHandler
+}
+
+func (h *ActionHandler) configure(actionSchema *types.Schema, actions ...string) {
+	if actionSchema.ResourceActions == nil {
+		actionSchema.ResourceActions = map[string]types.Action{}
+	}
+	for _, action := range actions {
+		actionSchema.ResourceActions[action] = actionDefinitions[action]
+	}
+	actionSchema.ActionHandler = h.ActionHandler
+	actionSchema.Formatter = h.Formatter
+}
+
+var actionDefinitions = map[string]types.Action{
+	ScaleAction:    {Input: "scaleInput"},
+	PauseAction:    {},
+	ResumeAction:   {},
+	RedeployAction: {},
+	RollbackAction: {Input: "rollbackInput"},
+}
+
+type ActionHandler struct {
+	K8sClient rest.Interface
+}
+
+// Formatter adds the actions the caller may run on the workload
+func (h *ActionHandler) Formatter(apiContext *types.APIContext, resource *types.RawResource) {
+	k, ok := kinds[typeOf(resource.ID)]
+	if !ok {
+		return
+	}
+
+	namespace, _ := resource.Values["namespaceId"].(string)
+	paused := convert.ToBool(resource.Values["paused"])
+	for _, action := range k.actions {
+		if (action == PauseAction && paused) || (action == ResumeAction && !paused) {
+			continue
+		}
+		if auth.Can(apiContext, k.permission(action, namespace)) {
+			resource.AddAction(apiContext, action)
+		}
+	}
+
+	if k.supports(RollbackAction) && auth.Can(apiContext, k.historyPermission(namespace)) {
+		resource.Links[RevisionsLink] = apiContext.URLBuilder.Link(RevisionsLink, resource)
+	}
+}
+
+// ActionHandler runs the action on the type the ID is prefixed with
+func (h *ActionHandler) ActionHandler(actionName string, action *types.Action, apiContext *types.APIContext) error {
+	typeName, shortID := splitTypeAndID(apiContext.ID)
+	k, ok := kinds[typeName]
+	if !ok || !k.supports(actionName) {
+		return httperror.NewAPIError(httperror.InvalidAction, fmt.Sprintf("Invalid action: %s", actionName))
+	}
+
+	parts := strings.SplitN(shortID, ":", 2)
+	if len(parts) != 2 {
+		return httperror.NewAPIError(httperror.NotFound, "invalid id "+apiContext.ID)
+	}
+	namespace, name := parts[0], parts[1]
+
+	var err error
+	switch actionName {
+	case ScaleAction:
+		err = h.scale(apiContext, k, namespace, name)
+	case PauseAction:
+		err = h.setPaused(apiContext, k, namespace, name, true)
+	case ResumeAction:
+		err = h.setPaused(apiContext, k, namespace, name, false)
+	case RedeployAction:
+		err = h.redeploy(apiContext, k, namespace, name)
+	case RollbackAction:
+		err = h.rollback(apiContext, k, namespace, name)
+	}
+	if err != nil {
+		return k8s.TranslateError(err)
+	}
+
+	var data map[string]interface{}
+	if err := access.ByID(apiContext, apiContext.Version, apiContext.Type, apiContext.ID, &data); err != nil {
+		return err
+	}
+	apiContext.WriteResponse(http.StatusOK, data)
+	return nil
+}
+
+func (h *ActionHandler) scale(apiContext *types.APIContext, k kind, namespace, name string) error {
+	input, err := parse.ReadBody(apiContext.Request)
+	if err != nil {
+		return err
+	}
+	if _, ok := input["replicas"]; !ok {
+		return httperror.NewFieldAPIError(httperror.MissingRequired, "replicas", "")
+	}
+	replicas, err := convert.ToNumber(input["replicas"])
+	if err != nil || replicas < 0 {
+		return httperror.NewFieldAPIError(httperror.InvalidFormat, "replicas", "must be a number of at least 0")
+	}
+
+	scale := &unstructured.Unstructured{}
+	if err := k.request(apiContext, h.K8sClient, http.MethodGet, namespace, name).
+		SubResource("scale").
+		Do().
+		Into(scale); err != nil {
+		return err
+	}
+
+	values.PutValue(scale.Object, replicas, "spec", "replicas")
+
+	return k.request(apiContext, h.K8sClient, http.MethodPut, namespace, name).
+		SubResource("scale").
+		Body(scale).
+		Do().
+		Error()
+}
+
+func (h *ActionHandler) setPaused(apiContext *types.APIContext, k kind, namespace, name string, paused bool) error {
+	return h.patch(apiContext, k, namespace, name, patchtype.MergePatchType, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"paused": paused,
+		},
+	})
+}
+
+func (h *ActionHandler) redeploy(apiContext *types.APIContext, k kind, namespace, name string) error {
+	return h.patch(apiContext, k, namespace, name, patchtype.MergePatchType, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{
+						redeployAnnotation: time.Now().UTC().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+}
+
+func (h *ActionHandler) rollback(apiContext *types.APIContext, k kind, namespace, name string) error {
+	input, err := parse.ReadBody(apiContext.Request)
+	if err != nil {
+		return err
+	}
+	target, err := convert.ToNumber(input["revision"])
+	if input["revision"] != nil && (err != nil || target < 0) {
+		return httperror.NewFieldAPIError(httperror.InvalidFormat, "revision", "must be a number of at least 0")
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := k.request(apiContext, h.K8sClient, http.MethodGet, namespace, name).Do().Into(obj); err != nil {
+		return err
+	}
+	if convert.ToBool(values.GetValueN(obj.Object, "spec", "paused")) {
+		return httperror.NewAPIError(httperror.InvalidState, "can not roll back a paused deployment, resume it first")
+	}
+
+	history, err := h.revisions(apiContext, k, obj)
+	if err != nil {
+		return err
+	}
+
+	current := currentRevision(k, obj, history)
+	rev, err := findRevision(history, current, target)
+	if err != nil {
+		return err
+	}
+
+	if rev.patch != nil {
+		return k.request(apiContext, h.K8sClient, http.MethodPatch, namespace, name).
+			SetHeader("Content-Type", string(patchtype.StrategicMergePatchType)).
+			Body(rev.patch).
+			Do().
+			Error()
+	}
+
+	return h.patch(apiContext, k, namespace, name, patchtype.JSONPatchType, []map[string]interface{}{
+		{
+			"op":    "replace",
+			"path":  "/spec/template",
+			"value": rev.template,
+		},
+	})
+}
+
+// findRevision returns revision target, or the one before current if target is 0
+func findRevision(history []revision, current, target int64) (*revision, error) {
+	if target == 0 {
+		for i := len(history) - 1; i >= 0; i-- {
+			if history[i].Number < current {
+				return &history[i], nil
+			}
+		}
+		return nil, httperror.NewAPIError(httperror.InvalidState, "no revision to roll back to")
+	}
+
+	if target == current {
+		return nil, httperror.NewFieldAPIError(httperror.InvalidOption, "revision", fmt.Sprintf("revision %d is the current revision", target))
+	}
+	for i := range history {
+		if history[i].Number == target {
+			return &history[i], nil
+		}
+	}
+	return nil, httperror.NewFieldAPIError(httperror.InvalidReference, "revision", fmt.Sprintf("revision %d not found", target))
+}
+
+func (h *ActionHandler) patch(apiContext *types.APIContext, k kind, namespace, name string, patchType patchtype.PatchType, patch interface{}) error {
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	return k.request(apiContext, h.K8sClient, http.MethodPatch, namespace, name).
+		SetHeader("Content-Type", string(patchType)).
+		Body(body).
+		Do().
+		Error()
+}
+
+func typeOf(id string) string {
+	typeName, _ := splitTypeAndID(id)
+	return typeName
+}
@@ -1,7 +1,10 @@
 package workload
 
 import (
+	"context"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/norman/types"
@@ -38,46 +41,108 @@ func (a *AggregateStore) ByID(apiContext *types.APIContext, schema *types.Schema
 }
 
 func (a *AggregateStore) Watch(apiContext *types.APIContext, schema *types.Schema, opt *types.QueryOptions) (chan map[string]interface{}, error) {
-	return a.CreateStore.Watch(apiContext, schema, opt)
+	cancelCtx, cancel := context.WithCancel(apiContext.Request.Context())
+	readerGroup, ctx := errgroup.WithContext(cancelCtx)
+
+	// Every sub store stops its watch when the request context is done, so give them
+	// one that is also cancelled as soon as any of the other streams fails
+	watchContext := *apiContext
+	watchContext.Request = apiContext.Request.WithContext(ctx)
+
+	var streams []chan map[string]interface{}
+	for typeName, store := range a.Stores {
+		c, err := store.Watch(&watchContext, a.Schemas[typeName], opt)
+		if err != nil {
+			cancel()
+			for _, c := range streams {
+				go drain(c)
+			}
+			return nil, err
+		}
+		if c != nil {
+			streams = append(streams, c)
+		}
+	}
+
+	events := make(chan map[string]interface{})
+	for _, c := range streams {
+		streamEvents(ctx, cancel, readerGroup, c, events)
+	}
+
+	go func() {
+		readerGroup.Wait()
+		cancel()
+		close(events)
+	}()
+
+	return events, nil
 }
 
-func (a *AggregateStore) List(apiContext *types.APIContext, schema *types.Schema, opt *types.QueryOptions) ([]map[string]interface{}, error) {
-	items := make(chan map[string]interface{})
+// streamEvents copies c to result. When c ends, so does the aggregate watch: the
+// other types would otherwise go on without this one, and the caller can only
+// watch again as a whole.
+func streamEvents(ctx context.Context, cancel context.CancelFunc, eg *errgroup.Group, c chan map[string]interface{}, result chan map[string]interface{}) {
+	eg.Go(func() error {
+		defer cancel()
+		for item := range c {
+			select {
+			case result <- item:
+			case <-ctx.Done():
+				go drain(c)
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+}
 
-	g, ctx := errgroup.WithContext(apiContext.Request.Context())
+// drain lets a sub store finish writing to c, so it can shut down
+func drain(c chan map[string]interface{}) {
+	for range c {
+	}
+}
 
-	submit := func(schema *types.Schema, store types.Store) {
+// List lists every type. Conditions, sorting and paging are left to the query
+// filter, which does them once on the combined list.
+func (a *AggregateStore) List(apiContext *types.APIContext, schema *types.Schema, opt *types.QueryOptions) ([]map[string]interface{}, error) {
+	var (
+		lock  sync.Mutex
+		lists = map[string][]map[string]interface{}{}
+	)
+
+	g := errgroup.Group{}
+	for typeName, store := range a.Stores {
+		typeName, store := typeName, store
 		g.Go(func() error {
-			data, err := store.List(apiContext, schema, opt)
+			data, err := store.List(apiContext, a.Schemas[typeName], opt)
 			if err != nil {
 				return err
 			}
-			for _, item := range data {
-				select {
-				case items <- item:
-				case <-ctx.Done():
-					return ctx.Err()
-				}
-			}
+
+			lock.Lock()
+			lists[typeName] = data
+			lock.Unlock()
 			return nil
 		})
 	}
 
-	for typeName, store := range a.Stores {
-		submit(a.Schemas[typeName], store)
+	if err := g.Wait(); err != nil {
+		return nil, err
 	}
 
-	go func() {
-		g.Wait()
-		close(items)
-	}()
+	// Combined in a fixed type order so the list comes out the same way every time
+	var typeNames []string
+	for typeName := range lists {
+		typeNames = append(typeNames, typeName)
+	}
+	sort.Strings(typeNames)
 
 	var result []map[string]interface{}
-	for item := range items {
-		result = append(result, item)
+	for _, typeName := range typeNames {
+		result = append(result, lists[typeName]...)
 	}
 
-	return result, g.Wait()
+	return result, nil
 }
 
 func (a *AggregateStore) Create(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}) (map[string]interface{}, error) {
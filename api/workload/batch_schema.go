@@ -0,0 +1,87 @@
+package workload
+
+import (
+	"github.com/rancher/norman/types"
+	m "github.com/rancher/norman/types/mapper"
+	"github.com/rancher/types/apis/project.cattle.io/v3"
+	"github.com/rancher/types/apis/project.cattle.io/v3/schema"
+	"github.com/rancher/types/factory"
+	"github.com/rancher/types/mapper"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+)
+
+const (
+	JobType     = "job"
+	CronJobType = "cronJob"
+)
+
+type deployOverride struct {
+	v3.DeployConfig
+}
+
+type projectOverride struct {
+	types.Namespaced
+	ProjectID string `norman:"type=reference[/v3/schemas/project],noupdate"`
+}
+
+// AddBatchSchemas imports job and cronJob, which rancher/types does not define,
+// in the same shape as the other workload types.
+func AddBatchSchemas(schemas *types.Schemas) {
+	batchSchemas := factory.Schemas(&schema.Version).
+		AddSchemas(schemas).
+		Init(jobTypes).
+		Init(cronJobTypes)
+
+	for _, batchSchema := range batchSchemas.Schemas() {
+		if schemas.Schema(&batchSchema.Version, batchSchema.ID) == nil {
+			schemas.AddSchema(*batchSchema)
+		}
+	}
+}
+
+func jobTypes(schemas *types.Schemas) *types.Schemas {
+	return schemas.
+		AddMapperForType(&schema.Version, batchv1.JobSpec{},
+			&m.Move{
+				From:        "completions",
+				To:          "scale",
+				DestDefined: true,
+			},
+			&m.Move{
+				From: "parallelism",
+				To:   "deploymentStrategy/jobConfig/batchLimit",
+			},
+			&m.Move{
+				From: "activeDeadlineSeconds",
+				To:   "deploymentStrategy/jobConfig/activeDeadlineSeconds",
+			},
+			m.Drop{Field: "selector"},
+			m.Drop{Field: "manualSelector"},
+			&m.Embed{Field: "template"},
+		).
+		AddMapperForType(&schema.Version, batchv1.Job{}, mapper.NewWorkloadTypeMapper()).
+		MustImport(&schema.Version, batchv1.JobSpec{}, deployOverride{}).
+		MustImportAndCustomize(&schema.Version, batchv1.Job{}, func(schema *types.Schema) {
+			schema.BaseType = "workload"
+		}, projectOverride{})
+}
+
+func cronJobTypes(schemas *types.Schemas) *types.Schemas {
+	return schemas.
+		AddMapperForType(&schema.Version, batchv1beta1.JobTemplateSpec{},
+			// The pod template brings its own metadata, which the job metadata would collide with
+			m.Drop{Field: "metadata"},
+			&m.Embed{Field: "spec"},
+		).
+		AddMapperForType(&schema.Version, batchv1beta1.CronJobSpec{},
+			&m.Embed{Field: "jobTemplate"},
+		).
+		AddMapperForType(&schema.Version, batchv1beta1.CronJob{}, mapper.NewWorkloadTypeMapper()).
+		MustImport(&schema.Version, batchv1beta1.CronJobSpec{}, struct {
+			ConcurrencyPolicy string `json:"concurrencyPolicy" norman:"type=enum,options=Allow|Forbid|Replace,default=Allow"`
+		}{}).
+		MustImportAndCustomize(&schema.Version, batchv1beta1.CronJob{}, func(schema *types.Schema) {
+			schema.BaseType = "workload"
+		}, projectOverride{})
+}
@@ -1,75 +1,170 @@
 package workload
 
 import (
+	"context"
 	"fmt"
-
 	"strings"
 
-	"github.com/rancher/norman/api/access"
+	"github.com/rancher/cluster-api/auth"
 	"github.com/rancher/norman/types"
-	"github.com/rancher/norman/types/definition"
 	"github.com/rancher/norman/types/values"
-	"github.com/rancher/types/apis/project.cattle.io/v3/schema"
-	"github.com/rancher/types/client/project/v3"
+	appsv1beta2 "k8s.io/api/apps/v1beta2"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 )
 
-func OwnerMap(context *types.APIContext) (map[string]string, error) {
-	result := map[string]string{}
+type ownerInformer struct {
+	informer    cache.SharedIndexInformer
+	authContext map[string]string
+}
+
+// OwnerCache follows the controller owner references of workloads from shared
+// informers, so resolving the workload of a pod does not list every workload. It
+// keeps the pods too, which the workload state is worked out from.
+type OwnerCache struct {
+	informers map[string]ownerInformer
+	pods      cache.SharedIndexInformer
+}
+
+func NewOwnerCache(ctx context.Context, k8sClient kubernetes.Interface) (*OwnerCache, error) {
+	apps := k8sClient.AppsV1beta2().RESTClient()
+	batch := k8sClient.BatchV1().RESTClient()
+	batchBeta := k8sClient.BatchV1beta1().RESTClient()
+	core := k8sClient.CoreV1().RESTClient()
 
-	var workloads []client.Workload
-	if err := access.List(context, &schema.Version, client.WorkloadType, &types.QueryOptions{
-		Options: map[string]string{
-			"hidden": "true",
+	o := &OwnerCache{
+		informers: map[string]ownerInformer{},
+		pods: cache.NewSharedIndexInformer(
+			cache.NewListWatchFromClient(core, "pods", "", fields.Everything()),
+			&corev1.Pod{}, 0, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}),
+	}
+	o.add("CronJob", batchBeta, "batch", "cronjobs", &batchv1beta1.CronJob{})
+	o.add("DaemonSet", apps, "apps", "daemonsets", &appsv1beta2.DaemonSet{})
+	o.add("Deployment", apps, "apps", "deployments", &appsv1beta2.Deployment{})
+	o.add("Job", batch, "batch", "jobs", &batchv1.Job{})
+	o.add("ReplicaSet", apps, "apps", "replicasets", &appsv1beta2.ReplicaSet{})
+	o.add("ReplicationController", core, "", "replicationcontrollers", &corev1.ReplicationController{})
+	o.add("StatefulSet", apps, "apps", "statefulsets", &appsv1beta2.StatefulSet{})
+
+	go o.pods.Run(ctx.Done())
+	synced := []cache.InformerSynced{o.pods.HasSynced}
+	for _, owner := range o.informers {
+		go owner.informer.Run(ctx.Done())
+		synced = append(synced, owner.informer.HasSynced)
+	}
+
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return nil, fmt.Errorf("failed to sync workload owner cache")
+	}
+
+	return o, nil
+}
+
+func (o *OwnerCache) add(kind string, client rest.Interface, group, resource string, obj runtime.Object) {
+	o.informers[strings.ToLower(kind)] = ownerInformer{
+		informer: cache.NewSharedIndexInformer(
+			cache.NewListWatchFromClient(client, resource, "", fields.Everything()),
+			obj, 0, cache.Indexers{}),
+		authContext: map[string]string{
+			"apiGroup": group,
+			"resource": resource,
 		},
-	}, &workloads); err != nil {
-		return nil, err
 	}
+}
 
-	for _, workload := range workloads {
-		for _, owner := range workload.OwnerReferences {
-			if owner.Controller != nil && *owner.Controller {
-				id := key(definition.GetShortTypeFromFull(workload.Type), workload.Name)
-				result[id] = key(owner.Kind, owner.Name)
-			}
-		}
+// owner returns the controller of the given workload. Workloads the caller can't
+// see are treated as having no owner, so nothing is revealed about their parents.
+func (o *OwnerCache) owner(apiContext *types.APIContext, kind, namespace, name string) (string, string) {
+	owner, ok := o.informers[strings.ToLower(kind)]
+	if !ok {
+		return "", ""
+	}
+
+	obj, exists, err := owner.informer.GetIndexer().GetByKey(namespace + "/" + name)
+	if err != nil || !exists {
+		return "", ""
+	}
+
+	if apiContext.AccessControl.Filter(apiContext, map[string]interface{}{
+		"id":          namespace + ":" + name,
+		"namespaceId": namespace,
+	}, owner.authContext) == nil {
+		return "", ""
+	}
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", ""
 	}
 
-	return result, nil
+	if controller := metav1.GetControllerOf(accessor); controller != nil {
+		return controller.Kind, controller.Name
+	}
+	return "", ""
 }
 
-func ResolveWorkloadID(data map[string]interface{}, owners map[string]string) string {
-	workloadID := ""
+// Pods returns the pods of namespace, or none if the caller can't list them there
+func (o *OwnerCache) Pods(apiContext *types.APIContext, namespace string) []*corev1.Pod {
+	if !auth.Can(apiContext, auth.Permission{Verb: "list", Resource: "pods", Namespace: namespace}) {
+		return nil
+	}
+
+	objs, err := o.pods.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+	if err != nil {
+		return nil
+	}
+
+	var result []*corev1.Pod
+	for _, obj := range objs {
+		if pod, ok := obj.(*corev1.Pod); ok {
+			result = append(result, pod)
+		}
+	}
+	return result
+}
+
+func (o *OwnerCache) ResolveWorkloadID(apiContext *types.APIContext, data map[string]interface{}) string {
+	kind, name := "", ""
 
 	ownerReferences, ok := values.GetSlice(data, "ownerReferences")
 	if !ok {
 		return ""
 	}
 
+	namespace, _ := data["namespaceId"].(string)
+
 	for _, ownerReference := range ownerReferences {
 		controller, _ := ownerReference["controller"].(bool)
 		if !controller {
 			continue
 		}
 
-		kind, _ := ownerReference["kind"].(string)
-		name, _ := ownerReference["name"].(string)
-		parent := key(kind, name)
-		workloadID = ""
-		for parent != "" {
-			workloadID = parent
-			parent = owners[workloadID]
-		}
+		kind, _ = ownerReference["kind"].(string)
+		name, _ = ownerReference["name"].(string)
 	}
 
-	if workloadID == "" {
+	if kind == "" {
 		return ""
 	}
 
-	parts := strings.SplitN(workloadID, "/", 2)
-	namespace, _ := data["namespaceId"].(string)
-	return fmt.Sprintf("%s:%s:%s", parts[0], namespace, parts[1])
-}
+	// Follow the controllers up, for example pod -> job -> cronJob
+	seen := map[string]bool{}
+	for !seen[kind+"/"+name] {
+		seen[kind+"/"+name] = true
+		parentKind, parentName := o.owner(apiContext, kind, namespace, name)
+		if parentKind == "" {
+			break
+		}
+		kind, name = parentKind, parentName
+	}
 
-func key(kind, name string) string {
-	return strings.ToLower(fmt.Sprintf("%s/%s", kind, name))
+	return strings.ToLower(fmt.Sprintf("%s:%s:%s", kind, namespace, name))
 }
@@ -0,0 +1,266 @@
+package workload
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/cluster-api/k8s"
+	"github.com/rancher/norman/api/access"
+	"github.com/rancher/norman/api/handler"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/types/apis/project.cattle.io/v3/schema"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	RevisionsLink = "revisions"
+
+	workloadRevisionType = "workloadRevision"
+	revisionDiffType     = "revisionDiff"
+)
+
+// WorkloadRevision is a revision of a workload, with the pod template in the
+// same shape as the workload's. Revisions have no ID, they are only read
+// through the revisions link.
+type WorkloadRevision struct {
+	Type     string                 `json:"type,omitempty"`
+	Revision int64                  `json:"revision,omitempty"`
+	Name     string                 `json:"name,omitempty"`
+	Created  string                 `json:"created,omitempty"`
+	Current  bool                   `json:"current,omitempty"`
+	Template map[string]interface{} `json:"template,omitempty"`
+}
+
+// RevisionDiff lists what changed in the pod template from one revision to another
+type RevisionDiff struct {
+	Type    string           `json:"type,omitempty"`
+	From    int64            `json:"from,omitempty"`
+	To      int64            `json:"to,omitempty"`
+	Changes []TemplateChange `json:"changes,omitempty"`
+}
+
+// TemplateChange is a value that was added, changed or removed. Path is made of
+// field names, the names of named items like containers, and list indexes.
+type TemplateChange struct {
+	Path string      `json:"path,omitempty"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// ListHandler serves the revisions link. It lists the revisions, or with
+// from=<revision> the diff to to=<revision>, which is the current one if left out.
+func (h *ActionHandler) ListHandler(apiContext *types.APIContext) error {
+	switch apiContext.Link {
+	case "":
+		return handler.ListHandler(apiContext)
+	case RevisionsLink:
+	default:
+		return apiContext.Schema.LinkHandler(apiContext)
+	}
+
+	if err := access.ByID(apiContext, apiContext.Version, apiContext.Type, apiContext.ID, nil); err != nil {
+		return err
+	}
+
+	typeName, shortID := splitTypeAndID(apiContext.ID)
+	k, ok := kinds[typeName]
+	parts := strings.SplitN(shortID, ":", 2)
+	if !ok || !k.supports(RollbackAction) || len(parts) != 2 {
+		return httperror.NewAPIError(httperror.NotFound, "Link not found")
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := k.request(apiContext, h.K8sClient, http.MethodGet, parts[0], parts[1]).Do().Into(obj); err != nil {
+		return k8s.TranslateError(err)
+	}
+
+	history, err := h.revisions(apiContext, k, obj)
+	if err != nil {
+		return k8s.TranslateError(err)
+	}
+	current := currentRevision(k, obj, history)
+
+	templates := map[int64]map[string]interface{}{}
+	var result []map[string]interface{}
+	for _, rev := range history {
+		template := mapTemplate(apiContext, rev.template)
+		templates[rev.Number] = template
+		result = append(result, map[string]interface{}{
+			"type":     workloadRevisionType,
+			"revision": rev.Number,
+			"name":     rev.Name,
+			"created":  rev.Created,
+			"current":  rev.Number == current,
+			"template": template,
+		})
+	}
+
+	query := apiContext.Request.URL.Query()
+	if query.Get("from") == "" {
+		apiContext.WriteResponse(http.StatusOK, result)
+		return nil
+	}
+
+	from, err := revisionParam(query.Get("from"), current)
+	if err != nil {
+		return err
+	}
+	to, err := revisionParam(query.Get("to"), current)
+	if err != nil {
+		return err
+	}
+
+	fromTemplate, ok := templates[from]
+	if !ok {
+		return httperror.NewFieldAPIError(httperror.InvalidReference, "from", fmt.Sprintf("revision %d not found", from))
+	}
+	toTemplate, ok := templates[to]
+	if !ok {
+		return httperror.NewFieldAPIError(httperror.InvalidReference, "to", fmt.Sprintf("revision %d not found", to))
+	}
+
+	changes := []map[string]interface{}{}
+	for _, change := range diff("", fromTemplate, toTemplate, nil) {
+		changes = append(changes, map[string]interface{}{
+			"path": change.Path,
+			"old":  change.Old,
+			"new":  change.New,
+		})
+	}
+
+	apiContext.WriteResponse(http.StatusOK, map[string]interface{}{
+		"type":    revisionDiffType,
+		"from":    from,
+		"to":      to,
+		"changes": changes,
+	})
+	return nil
+}
+
+func revisionParam(value string, current int64) (int64, error) {
+	if value == "" {
+		return current, nil
+	}
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, httperror.NewAPIError(httperror.InvalidFormat, "invalid revision "+value)
+	}
+	return revision, nil
+}
+
+// mapTemplate maps a pod template the way the workload schemas map theirs
+func mapTemplate(apiContext *types.APIContext, template map[string]interface{}) map[string]interface{} {
+	if template == nil {
+		return nil
+	}
+
+	data := (&unstructured.Unstructured{Object: template}).DeepCopy().Object
+	if templateSchema := apiContext.Schemas.Schema(&schema.Version, "podTemplateSpec"); templateSchema != nil && templateSchema.Mapper != nil {
+		templateSchema.Mapper.FromInternal(data)
+	}
+	return dropTypes(data).(map[string]interface{})
+}
+
+// dropTypes removes the type the mappers set on nested objects, it says nothing
+// about the template
+func dropTypes(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		delete(v, "type")
+		for k, item := range v {
+			v[k] = dropTypes(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = dropTypes(item)
+		}
+	}
+	return value
+}
+
+func diff(path string, old, new interface{}, changes []TemplateChange) []TemplateChange {
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		for _, key := range keys(oldMap, newMap) {
+			changes = diff(join(path, key), oldMap[key], newMap[key], changes)
+		}
+		return changes
+	}
+
+	oldSlice, oldIsSlice := old.([]interface{})
+	newSlice, newIsSlice := new.([]interface{})
+	if oldIsSlice && newIsSlice {
+		if oldNamed, ok := byName(oldSlice); ok {
+			if newNamed, ok := byName(newSlice); ok {
+				return diff(path, oldNamed, newNamed, changes)
+			}
+		}
+
+		for i := 0; i < len(oldSlice) || i < len(newSlice); i++ {
+			var oldItem, newItem interface{}
+			if i < len(oldSlice) {
+				oldItem = oldSlice[i]
+			}
+			if i < len(newSlice) {
+				newItem = newSlice[i]
+			}
+			changes = diff(join(path, strconv.Itoa(i)), oldItem, newItem, changes)
+		}
+		return changes
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		changes = append(changes, TemplateChange{
+			Path: path,
+			Old:  old,
+			New:  new,
+		})
+	}
+	return changes
+}
+
+// byName keys a list of named items like containers by name, so changes are
+// reported against the container and not its position
+func byName(items []interface{}) (map[string]interface{}, bool) {
+	result := map[string]interface{}{}
+	for _, item := range items {
+		name := convert.ToString(convert.ToMapInterface(item)["name"])
+		if name == "" {
+			return nil, false
+		}
+		if _, ok := result[name]; ok {
+			return nil, false
+		}
+		result[name] = item
+	}
+	return result, true
+}
+
+func keys(maps ...map[string]interface{}) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, m := range maps {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				result = append(result, key)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "/" + key
+}
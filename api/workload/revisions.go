@@ -0,0 +1,154 @@
+package workload
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/norman/types/values"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+	podTemplateHashLabel         = "pod-template-hash"
+)
+
+// revision is a pod template a workload had. Deployments keep them in their
+// replica sets, daemon sets and stateful sets in controller revisions.
+type revision struct {
+	Number  int64
+	Name    string
+	Created string
+	// template is the pod template as it is in the revision
+	template map[string]interface{}
+	// patch is the strategic merge patch of a controller revision that restores it
+	patch []byte
+}
+
+// revisions returns the revisions of obj, oldest first
+func (h *ActionHandler) revisions(apiContext *types.APIContext, k kind, obj *unstructured.Unstructured) ([]revision, error) {
+	selector, err := labelSelector(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	source := controllerRevisions
+	if k.resource == "deployments" {
+		source = kinds["replicaset"]
+	}
+
+	list := &unstructured.UnstructuredList{}
+	if err := source.request(apiContext, h.K8sClient, http.MethodGet, obj.GetNamespace(), "").
+		Param("labelSelector", selector).
+		Do().
+		Into(list); err != nil {
+		return nil, err
+	}
+
+	var result []revision
+	for _, item := range list.Items {
+		if !ownedBy(&item, obj) {
+			continue
+		}
+
+		rev := revision{
+			Name:    item.GetName(),
+			Created: convert.ToString(values.GetValueN(item.Object, "metadata", "creationTimestamp")),
+		}
+
+		if k.resource == "deployments" {
+			number, err := strconv.ParseInt(item.GetAnnotations()[deploymentRevisionAnnotation], 10, 64)
+			if err != nil {
+				continue
+			}
+			rev.Number = number
+			rev.template = podTemplate(&item)
+		} else {
+			number, err := convert.ToNumber(item.Object["revision"])
+			if err != nil {
+				continue
+			}
+			rev.Number = number
+			if rev.patch, err = json.Marshal(item.Object["data"]); err != nil {
+				return nil, err
+			}
+			rev.template = patchTemplate(item.Object["data"])
+		}
+
+		result = append(result, rev)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Number < result[j].Number
+	})
+
+	return result, nil
+}
+
+// currentRevision is the revision obj runs, which for a daemon set or stateful
+// set is the newest one
+func currentRevision(k kind, obj *unstructured.Unstructured, history []revision) int64 {
+	if k.resource == "deployments" {
+		number, _ := strconv.ParseInt(obj.GetAnnotations()[deploymentRevisionAnnotation], 10, 64)
+		return number
+	}
+	if len(history) == 0 {
+		return 0
+	}
+	return history[len(history)-1].Number
+}
+
+// podTemplate returns the pod template of a replica set as the deployment had it
+func podTemplate(replicaSet *unstructured.Unstructured) map[string]interface{} {
+	template, ok := values.GetValueN(replicaSet.DeepCopy().Object, "spec", "template").(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if labels, ok := values.GetValueN(template, "metadata", "labels").(map[string]interface{}); ok {
+		delete(labels, podTemplateHashLabel)
+	}
+	return template
+}
+
+// patchTemplate returns the pod template a controller revision restores
+func patchTemplate(data interface{}) map[string]interface{} {
+	template, ok := values.GetValueN(convert.ToMapInterface(data), "spec", "template").(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	result := map[string]interface{}{}
+	for k, v := range template {
+		if k != "$patch" {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+func labelSelector(obj *unstructured.Unstructured) (string, error) {
+	labelSelector := &metav1.LabelSelector{}
+	if err := convert.ToObj(values.GetValueN(obj.Object, "spec", "selector"), labelSelector); err != nil {
+		return "", err
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return "", err
+	}
+	return selector.String(), nil
+}
+
+func ownedBy(item, owner *unstructured.Unstructured) bool {
+	for _, ref := range item.GetOwnerReferences() {
+		if ref.UID == owner.GetUID() && ref.Controller != nil && *ref.Controller {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,293 @@
+package workload
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/norman/types/definition"
+	"github.com/rancher/norman/types/values"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	activeState       = "active"
+	updatingState     = "updating"
+	degradedState     = "degraded"
+	pausedState       = "paused"
+	scaledToZeroState = "scaled-to-zero"
+	succeededState    = "succeeded"
+	failedState       = "failed"
+	suspendedState    = "suspended"
+)
+
+// startingReasons are reasons a container waits for as part of starting it
+var startingReasons = map[string]bool{
+	"":                  true,
+	"ContainerCreating": true,
+	"PodInitializing":   true,
+}
+
+// StartingReason reports if a container waiting for reason is just starting, no
+// reason means it isn't waiting at all. Any other reason is a problem.
+func StartingReason(reason string) bool {
+	return startingReasons[reason]
+}
+
+// replicas are the pod counts a workload reports in its status
+type replicas struct {
+	desired int64
+	current int64
+	ready   int64
+	updated int64
+}
+
+// StateTransformer sets state, transitioning and transitioningMessage on
+// workloads. The status mapper only looks at conditions, which most workload
+// types don't have or don't keep up to date, so the state comes from the
+// replica counts and, when pods are missing, from what the pods are waiting on.
+type StateTransformer struct {
+	Owners *OwnerCache
+}
+
+func (t *StateTransformer) Transform(apiContext *types.APIContext, data map[string]interface{}) (map[string]interface{}, error) {
+	if data == nil {
+		return data, nil
+	}
+	return t.setState(apiContext, data), nil
+}
+
+func (t *StateTransformer) StreamTransform(apiContext *types.APIContext, data chan map[string]interface{}) (chan map[string]interface{}, error) {
+	if data == nil {
+		return nil, nil
+	}
+	return convert.Chan(data, func(item map[string]interface{}) map[string]interface{} {
+		if item[".removed"] == true {
+			return item
+		}
+		item, _ = t.Transform(apiContext, item)
+		return item
+	}), nil
+}
+
+func (t *StateTransformer) setState(apiContext *types.APIContext, data map[string]interface{}) map[string]interface{} {
+	// Left as the status mapper set it, it knows about finalizers
+	if data["state"] == "removing" {
+		return data
+	}
+
+	state, transitioning, message := activeState, "no", ""
+	switch definition.GetType(data) {
+	case JobType:
+		state, transitioning, message = jobState(data)
+	case CronJobType:
+		if convert.ToBool(data["suspend"]) {
+			state = suspendedState
+		}
+	default:
+		r, ok := replicaCounts(data)
+		if !ok {
+			return data
+		}
+		state, transitioning, message = replicaState(data, r)
+	}
+
+	if transitioning != "error" && needsPods(data) {
+		if problems := podProblems(t.workloadPods(apiContext, data)); len(problems) > 0 {
+			state, transitioning, message = degradedState, "error", strings.Join(problems, ", ")
+		}
+	}
+
+	data["state"] = state
+	data["transitioning"] = transitioning
+	data["transitioningMessage"] = message
+	return data
+}
+
+func replicaState(data map[string]interface{}, r replicas) (string, string, string) {
+	if message := failedCondition(data); message != "" {
+		return degradedState, "error", message
+	}
+
+	if convert.ToBool(data["paused"]) {
+		return pausedState, "no", ""
+	}
+
+	if r.desired == 0 {
+		if r.current > 0 {
+			return updatingState, "yes", fmt.Sprintf("Scaling down, %d pods left", r.current)
+		}
+		return scaledToZeroState, "no", ""
+	}
+
+	if r.updated < r.desired || r.current > r.desired {
+		return updatingState, "yes", fmt.Sprintf("%d of %d pods updated, %d ready", r.updated, r.desired, r.ready)
+	}
+	if r.ready < r.desired {
+		return updatingState, "yes", fmt.Sprintf("%d of %d pods ready", r.ready, r.desired)
+	}
+
+	return activeState, "no", ""
+}
+
+func jobState(data map[string]interface{}) (string, string, string) {
+	for _, condition := range conditions(data) {
+		if condition["status"] != "True" {
+			continue
+		}
+		switch condition["type"] {
+		case "Complete":
+			return succeededState, "no", ""
+		case "Failed":
+			return failedState, "error", convert.ToString(condition["message"])
+		}
+	}
+
+	active, _ := convert.ToNumber(values.GetValueN(data, "status", "active"))
+	if active > 0 {
+		return activeState, "yes", fmt.Sprintf("%d pods running", active)
+	}
+	return activeState, "no", ""
+}
+
+// replicaCounts reads the counts of the replicated workload types, daemon sets
+// count in nodes scheduled instead
+func replicaCounts(data map[string]interface{}) (replicas, bool) {
+	status, ok := data["status"].(map[string]interface{})
+	if !ok {
+		return replicas{}, false
+	}
+
+	number := func(value interface{}) int64 {
+		n, _ := convert.ToNumber(value)
+		return n
+	}
+
+	if definition.GetType(data) == "daemonSet" {
+		return replicas{
+			desired: number(status["desiredNumberScheduled"]),
+			current: number(status["currentNumberScheduled"]),
+			ready:   number(status["numberReady"]),
+			updated: number(status["updatedNumberScheduled"]),
+		}, true
+	}
+
+	r := replicas{
+		desired: number(data["scale"]),
+		current: number(status["replicas"]),
+		ready:   number(status["readyReplicas"]),
+		updated: number(status["updatedReplicas"]),
+	}
+	if data["scale"] == nil {
+		r.desired = 1
+	}
+	// Replica sets and replication controllers don't roll out, all their pods are current
+	if typeName := definition.GetType(data); typeName == "replicaSet" || typeName == "replicationController" {
+		r.updated = r.current
+	}
+	return r, true
+}
+
+// failedCondition is the message of a condition saying the workload can't make
+// progress
+func failedCondition(data map[string]interface{}) string {
+	for _, condition := range conditions(data) {
+		switch {
+		case condition["type"] == "ReplicaFailure" && condition["status"] == "True",
+			condition["type"] == "Progressing" && condition["status"] == "False":
+			return convert.ToString(condition["message"])
+		}
+	}
+	return ""
+}
+
+func conditions(data map[string]interface{}) []map[string]interface{} {
+	return convert.ToMapSlice(values.GetValueN(data, "status", "conditions"))
+}
+
+// needsPods is whether the workload is missing pods, which is when the pods may
+// say why
+func needsPods(data map[string]interface{}) bool {
+	if data["state"] == "removing" || convert.ToBool(data["paused"]) {
+		return false
+	}
+
+	switch definition.GetType(data) {
+	case JobType:
+		active, _ := convert.ToNumber(values.GetValueN(data, "status", "active"))
+		return active > 0
+	case CronJobType:
+		return false
+	}
+
+	r, ok := replicaCounts(data)
+	return ok && r.ready < r.desired
+}
+
+// workloadPods picks the pods data controls, directly or through the workloads
+// it controls, the same way pods get their workloadId
+func (t *StateTransformer) workloadPods(apiContext *types.APIContext, data map[string]interface{}) []*corev1.Pod {
+	id := convert.ToString(data["id"])
+
+	var result []*corev1.Pod
+	for _, pod := range t.Owners.Pods(apiContext, convert.ToString(data["namespaceId"])) {
+		controller := metav1.GetControllerOf(pod)
+		if controller == nil {
+			continue
+		}
+
+		if strings.ToLower(fmt.Sprintf("%s:%s:%s", controller.Kind, pod.Namespace, controller.Name)) == id ||
+			t.Owners.ResolveWorkloadID(apiContext, map[string]interface{}{
+				"namespaceId": pod.Namespace,
+				"ownerReferences": []interface{}{
+					map[string]interface{}{
+						"controller": true,
+						"kind":       controller.Kind,
+						"name":       controller.Name,
+					},
+				},
+			}) == id {
+			result = append(result, pod)
+		}
+	}
+	return result
+}
+
+// podProblems lists why pods can't be scheduled or their containers can't start
+func podProblems(workloadPods []*corev1.Pod) []string {
+	seen := map[string]bool{}
+	var result []string
+	add := func(problem string) {
+		if !seen[problem] {
+			seen[problem] = true
+			result = append(result, problem)
+		}
+	}
+
+	for _, pod := range workloadPods {
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse && condition.Reason == corev1.PodReasonUnschedulable {
+				add(condition.Message)
+			}
+		}
+
+		for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+			for _, container := range statuses {
+				reason := ""
+				if container.State.Waiting != nil {
+					reason = container.State.Waiting.Reason
+				}
+				if StartingReason(reason) {
+					continue
+				}
+				add(fmt.Sprintf("%s: %s", container.Name, reason))
+			}
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
@@ -1,11 +1,14 @@
 package workload
 
 import (
+	"github.com/rancher/cluster-api/api/query"
+	"github.com/rancher/norman/store/transform"
 	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
 	"github.com/rancher/types/apis/project.cattle.io/v3/schema"
 )
 
-func ConfigureStore(schemas *types.Schemas) {
+func ConfigureStore(schemas *types.Schemas, state *StateTransformer) {
 	workloadSchema := schemas.Schema(&schema.Version, "workload")
 
 	store := types.Store(&PrefixTypeStore{
@@ -13,19 +16,44 @@ func ConfigureStore(schemas *types.Schemas) {
 	})
 	workloadSchema.Store = store
 
-	store = NewAggregateStore(store,
-		workloadSchema,
+	kindSchemas := []*types.Schema{
 		schemas.Schema(&schema.Version, "deployment"),
 		schemas.Schema(&schema.Version, "replicaSet"),
 		schemas.Schema(&schema.Version, "replicationController"),
 		schemas.Schema(&schema.Version, "daemonSet"),
-		schemas.Schema(&schema.Version, "statefulSet"))
+		schemas.Schema(&schema.Version, "statefulSet"),
+		schemas.Schema(&schema.Version, JobType),
+		schemas.Schema(&schema.Version, CronJobType),
+	}
+
+	// Set on each kind, so a workload has the same state whether it's read as
+	// itself or through the workload schema
+	for _, kindSchema := range kindSchemas {
+		kindSchema.Store = &transform.Store{
+			Store:             kindSchema.Store,
+			Transformer:       state.Transform,
+			StreamTransformer: state.StreamTransform,
+		}
+	}
+
+	store = NewAggregateStore(store, append([]*types.Schema{workloadSchema}, kindSchemas...)...)
 
 	workloadSchema.Store = &workloadStore{
 		Store: store,
 	}
 }
 
+// ListHandler lists aggregated workloads with query.Filter, so they sort stably
+// across types and page with continue tokens. Links are left as they are.
+func ListHandler(next types.RequestHandler) types.RequestHandler {
+	return func(apiContext *types.APIContext) error {
+		if apiContext.Link == "" {
+			apiContext.QueryFilter = query.Filter
+		}
+		return next(apiContext)
+	}
+}
+
 type workloadStore struct {
 	types.Store
 }
@@ -50,3 +78,21 @@ func (w *workloadStore) List(apiContext *types.APIContext, schema *types.Schema,
 
 	return result, nil
 }
+
+func (w *workloadStore) Watch(apiContext *types.APIContext, schema *types.Schema, opt *types.QueryOptions) (chan map[string]interface{}, error) {
+	c, err := w.Store.Watch(apiContext, schema, opt)
+	if err != nil || c == nil {
+		return nil, err
+	}
+
+	if opt.Options["hidden"] == "true" {
+		return c, nil
+	}
+
+	return convert.Chan(c, func(data map[string]interface{}) map[string]interface{} {
+		if data["ownerReferences"] != nil {
+			return nil
+		}
+		return data
+	}), nil
+}
@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/rancher/norman/types"
+	"github.com/sirupsen/logrus"
+	authzv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	reviewCacheSize = 4096
+	reviewCacheTTL  = 10 * time.Second
+)
+
+// Resource is the kubernetes resource behind a schema
+type Resource struct {
+	Group      string
+	Resource   string
+	Namespaced bool
+}
+
+// Permission is a single check against the apiserver authorizer
+type Permission struct {
+	Verb        string
+	Group       string
+	Resource    string
+	Subresource string
+	Namespace   string
+}
+
+type cachedDecision struct {
+	allowed bool
+	expires time.Time
+}
+
+// AccessControl checks create, update and delete with SubjectAccessReviews for
+// the caller, so forbidden requests fail before being sent and UIs only get the
+// links they can use. Listing and filtering is left to the wrapped access control.
+type AccessControl struct {
+	types.AccessControl
+	k8sClient kubernetes.Interface
+	resources map[string]Resource
+	// typeResources are the resources keyed by the lower cased type prefix of
+	// aggregated workload IDs, like deployment:ns:name
+	typeResources map[string]Resource
+	cache         *lru.Cache
+}
+
+func NewAccessControl(accessControl types.AccessControl, k8sClient kubernetes.Interface, resources map[string]Resource) (*AccessControl, error) {
+	cache, err := lru.New(reviewCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	typeResources := map[string]Resource{}
+	for id, resource := range resources {
+		typeResources[strings.ToLower(id)] = resource
+	}
+
+	return &AccessControl{
+		AccessControl: accessControl,
+		k8sClient:     k8sClient,
+		resources:     resources,
+		typeResources: typeResources,
+		cache:         cache,
+	}, nil
+}
+
+// Can reports if the caller of the request has the permission. It is true if the
+// server isn't set up with this AccessControl.
+func Can(apiContext *types.APIContext, permission Permission) bool {
+	if a, ok := apiContext.AccessControl.(*AccessControl); ok {
+		return a.Can(apiContext, permission)
+	}
+	return true
+}
+
+func (a *AccessControl) CanCreate(apiContext *types.APIContext, schema *types.Schema) bool {
+	if !a.AccessControl.CanCreate(apiContext, schema) {
+		return false
+	}
+
+	resource, ok := a.resource(schema)
+	if !ok {
+		return true
+	}
+
+	if a.can(apiContext, "create", resource, "") {
+		return true
+	}
+
+	// The namespace is only known once the body is read, so namespaced creates are
+	// checked again by CheckCreate
+	return resource.Namespaced
+}
+
+// CheckCreate checks the create for the namespace the data will be created in
+func (a *AccessControl) CheckCreate(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}) bool {
+	resource, ok := a.resource(schema)
+	if !ok || !resource.Namespaced {
+		return true
+	}
+
+	namespace, _ := data["namespaceId"].(string)
+	return a.can(apiContext, "create", resource, namespace)
+}
+
+func (a *AccessControl) CanUpdate(apiContext *types.APIContext, obj map[string]interface{}, schema *types.Schema) bool {
+	if !a.AccessControl.CanUpdate(apiContext, obj, schema) {
+		return false
+	}
+	return a.canResource(apiContext, "update", obj, schema)
+}
+
+func (a *AccessControl) CanDelete(apiContext *types.APIContext, obj map[string]interface{}, schema *types.Schema) bool {
+	if !a.AccessControl.CanDelete(apiContext, obj, schema) {
+		return false
+	}
+	return a.canResource(apiContext, "delete", obj, schema)
+}
+
+func (a *AccessControl) canResource(apiContext *types.APIContext, verb string, obj map[string]interface{}, schema *types.Schema) bool {
+	// Checked before the object is loaded, obj is nil and the id is the request's
+	id := apiContext.ID
+	if obj != nil {
+		id, _ = obj["id"].(string)
+	}
+	typeName, idNamespace, _ := ParseID(id)
+
+	resource, ok := a.resource(schema)
+	if typeResource, isType := a.typeResources[typeName]; isType {
+		// The aggregate workload store writes to the type in the ID
+		resource, ok = typeResource, true
+	}
+	if !ok {
+		return true
+	}
+
+	namespace := ""
+	if resource.Namespaced {
+		namespace, _ = obj["namespaceId"].(string)
+		if namespace == "" {
+			namespace = idNamespace
+		}
+	}
+
+	return a.can(apiContext, verb, resource, namespace)
+}
+
+func (a *AccessControl) can(apiContext *types.APIContext, verb string, resource Resource, namespace string) bool {
+	return a.Can(apiContext, Permission{
+		Verb:      verb,
+		Group:     resource.Group,
+		Resource:  resource.Resource,
+		Namespace: namespace,
+	})
+}
+
+func (a *AccessControl) Can(apiContext *types.APIContext, permission Permission) bool {
+	user := apiContext.Request.Header.Get(userHeader)
+	groups := apiContext.Request.Header[http.CanonicalHeaderKey(groupHeader)]
+
+	key := cacheKey(user, groups, permission)
+	if cached, ok := a.cache.Get(key); ok {
+		decision := cached.(cachedDecision)
+		if time.Now().Before(decision.expires) {
+			return decision.allowed
+		}
+		a.cache.Remove(key)
+	}
+
+	review, err := a.k8sClient.AuthorizationV1().SubjectAccessReviews().Create(&authzv1.SubjectAccessReview{
+		Spec: authzv1.SubjectAccessReviewSpec{
+			User:   user,
+			Groups: groups,
+			ResourceAttributes: &authzv1.ResourceAttributes{
+				Verb:        permission.Verb,
+				Group:       permission.Group,
+				Resource:    permission.Resource,
+				Subresource: permission.Subresource,
+				Namespace:   permission.Namespace,
+			},
+		},
+	})
+	if err != nil {
+		// Not cached, the next request asks again
+		logrus.Errorf("Failed to review access for %s: %v", user, err)
+		return false
+	}
+
+	a.cache.Add(key, cachedDecision{
+		allowed: review.Status.Allowed,
+		expires: time.Now().Add(reviewCacheTTL),
+	})
+
+	return review.Status.Allowed
+}
+
+func (a *AccessControl) resource(schema *types.Schema) (Resource, bool) {
+	if resource, ok := a.resources[schema.ID]; ok {
+		return resource, true
+	}
+	resource, ok := a.resources[schema.BaseType]
+	return resource, ok
+}
+
+// ParseID splits an ID into the type prefix of aggregated workload IDs, like
+// deployment:ns:name, the namespace and the name. Other namespaced IDs are
+// namespace:name and cluster resources only have a name. Neither namespaces nor
+// names can have a colon, so the parts are never ambiguous.
+func ParseID(id string) (typeName, namespace, name string) {
+	parts := strings.SplitN(id, ":", 3)
+	switch len(parts) {
+	case 3:
+		return parts[0], parts[1], parts[2]
+	case 2:
+		return "", parts[0], parts[1]
+	default:
+		return "", "", parts[0]
+	}
+}
+
+func cacheKey(user string, groups []string, permission Permission) [sha256.Size]byte {
+	groups = append([]string{}, groups...)
+	sort.Strings(groups)
+	bytes, _ := json.Marshal([]interface{}{user, groups, permission})
+	return sha256.Sum256(bytes)
+}
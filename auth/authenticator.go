@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	userHeader  = "Impersonate-User"
+	groupHeader = "Impersonate-Group"
+
+	// Browsers can't set headers on websockets, so like the apiserver the token
+	// is also accepted as a subprotocol
+	websocketTokenPrefix = "base64url.bearer.authorization.k8s.io."
+	websocketProtocol    = "Sec-Websocket-Protocol"
+)
+
+type tokenProtocolKey struct{}
+
+type User struct {
+	Name   string
+	Groups []string
+}
+
+// Authenticator verifies a bearer token. It returns nil if the token is not
+// one it knows about, so the next authenticator can try.
+type Authenticator interface {
+	Authenticate(token string) (*User, error)
+}
+
+type Authenticators []Authenticator
+
+func (a Authenticators) Authenticate(token string) (*User, error) {
+	for _, authenticator := range a {
+		user, err := authenticator.Authenticate(token)
+		if err != nil || user != nil {
+			return user, err
+		}
+	}
+	return nil, nil
+}
+
+// NewHandler only lets through requests with a verified token and sets the
+// impersonation headers the stores and access control use to the verified identity
+func NewHandler(authenticator Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		for name := range req.Header {
+			if strings.HasPrefix(name, "Impersonate-") {
+				req.Header.Del(name)
+			}
+		}
+
+		token, protocol := bearerToken(req)
+		if token == "" {
+			unauthorized(rw)
+			return
+		}
+		if protocol != "" {
+			req = req.WithContext(context.WithValue(req.Context(), tokenProtocolKey{}, protocol))
+		}
+
+		user, err := authenticator.Authenticate(token)
+		if err != nil {
+			logrus.Errorf("Failed to authenticate request: %v", err)
+		}
+		if user == nil || user.Name == "" {
+			unauthorized(rw)
+			return
+		}
+
+		req.Header.Del("Authorization")
+		req.Header.Set(userHeader, user.Name)
+		for _, group := range user.Groups {
+			req.Header.Add(groupHeader, group)
+		}
+
+		next.ServeHTTP(rw, req)
+	})
+}
+
+func unauthorized(rw http.ResponseWriter) {
+	rw.Header().Set("WWW-Authenticate", `Bearer realm="cluster-api"`)
+	http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+}
+
+// bearerToken returns the token of the request, and the websocket subprotocol it
+// was sent in if it was
+func bearerToken(req *http.Request) (string, string) {
+	parts := strings.SplitN(req.Header.Get("Authorization"), " ", 2)
+	if len(parts) == 2 && strings.EqualFold(parts[0], "bearer") {
+		return strings.TrimSpace(parts[1]), ""
+	}
+
+	var (
+		token         string
+		tokenProtocol string
+		protocols     []string
+	)
+	for _, value := range req.Header[websocketProtocol] {
+		for _, protocol := range strings.Split(value, ",") {
+			protocol = strings.TrimSpace(protocol)
+			if !strings.HasPrefix(protocol, websocketTokenPrefix) {
+				protocols = append(protocols, protocol)
+				continue
+			}
+			decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(protocol, websocketTokenPrefix))
+			if err == nil {
+				token = string(decoded)
+				tokenProtocol = protocol
+			}
+		}
+	}
+
+	if token != "" {
+		// Don't pass the token on as a protocol to the apiserver
+		req.Header.Del(websocketProtocol)
+		if len(protocols) > 0 {
+			req.Header.Set(websocketProtocol, strings.Join(protocols, ", "))
+		}
+	}
+
+	return token, tokenProtocol
+}
+
+// WebsocketResponseHeader returns the header that accepts a websocket handshake
+// with the first of protocols the client offered, or else with the subprotocol
+// the client sent its token in. Browsers fail the handshake when the server
+// accepts none of the protocols they offered, or one they didn't offer.
+func WebsocketResponseHeader(req *http.Request, protocols ...string) http.Header {
+	offered := map[string]bool{}
+	for _, value := range req.Header[websocketProtocol] {
+		for _, protocol := range strings.Split(value, ",") {
+			offered[strings.TrimSpace(protocol)] = true
+		}
+	}
+
+	for _, protocol := range protocols {
+		if protocol != "" && offered[protocol] {
+			return http.Header{websocketProtocol: []string{protocol}}
+		}
+	}
+	if protocol, ok := req.Context().Value(tokenProtocolKey{}).(string); ok {
+		return http.Header{websocketProtocol: []string{protocol}}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+type staticAuthenticator struct {
+	token string
+}
+
+func (s staticAuthenticator) Authenticate(token string) (*User, error) {
+	if token != s.token {
+		return nil, nil
+	}
+	return &User{Name: "user"}, nil
+}
+
+func TestWebsocketHandshakeWithBearerProtocol(t *testing.T) {
+	tokenProtocol := websocketTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte("secret"))
+
+	tests := []struct {
+		name      string
+		offered   []string
+		supported []string
+		expected  string
+	}{
+		{
+			name:     "only the token protocol",
+			offered:  []string{tokenProtocol},
+			expected: tokenProtocol,
+		},
+		{
+			name:      "backend picked a protocol the client didn't offer",
+			offered:   []string{tokenProtocol},
+			supported: []string{"v4.channel.k8s.io"},
+			expected:  tokenProtocol,
+		},
+		{
+			name:      "a supported protocol next to the token",
+			offered:   []string{"v4.channel.k8s.io", tokenProtocol},
+			supported: []string{"v4.channel.k8s.io"},
+			expected:  "v4.channel.k8s.io",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var user string
+			upgrader := websocket.Upgrader{}
+			server := httptest.NewServer(NewHandler(staticAuthenticator{token: "secret"}, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				user = req.Header.Get(userHeader)
+				c, err := upgrader.Upgrade(rw, req, WebsocketResponseHeader(req, test.supported...))
+				if err != nil {
+					return
+				}
+				c.Close()
+			})))
+			defer server.Close()
+
+			dialer := websocket.Dialer{
+				Subprotocols: test.offered,
+			}
+			c, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+			if err != nil {
+				t.Fatalf("handshake failed: %v", err)
+			}
+			defer c.Close()
+
+			if user != "user" {
+				t.Errorf("expected the request to be authenticated, got user %q", user)
+			}
+			if protocol := resp.Header.Get(websocketProtocol); protocol != test.expected {
+				t.Errorf("expected protocol %q, got %q", test.expected, protocol)
+			}
+		})
+	}
+}
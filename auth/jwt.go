@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+}
+
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Groups    []string `json:"groups"`
+	Issuer    string   `json:"iss"`
+	Expires   *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+}
+
+type jwtAuthenticator struct {
+	issuer    string
+	secret    []byte
+	publicKey *rsa.PublicKey
+}
+
+// NewJWTAuthenticator verifies tokens signed with HS256, when the key file holds a
+// shared secret, or RS256, when it holds a PEM encoded RSA public key. The user is
+// taken from the sub claim and the groups from the groups claim.
+func NewJWTAuthenticator(keyFile, issuer string) (Authenticator, error) {
+	key, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &jwtAuthenticator{
+		issuer: issuer,
+	}
+
+	block, _ := pem.Decode(key)
+	if block == nil {
+		j.secret = []byte(strings.TrimSpace(string(key)))
+		if len(j.secret) == 0 {
+			return nil, fmt.Errorf("%s is empty", keyFile)
+		}
+		return j, nil
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := publicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an RSA public key", keyFile)
+	}
+	j.publicKey = rsaKey
+
+	return j, nil
+}
+
+func (j *jwtAuthenticator) Authenticate(token string) (*User, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		// Not a JWT, leave it to the other authenticators
+		return nil, nil
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, nil
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil
+	}
+
+	if err := j.verify(header.Algorithm, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, nil
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	if claims.Expires != nil && now >= *claims.Expires {
+		return nil, nil
+	}
+	if claims.NotBefore != nil && now < *claims.NotBefore {
+		return nil, nil
+	}
+	if j.issuer != "" && claims.Issuer != j.issuer {
+		return nil, nil
+	}
+
+	if claims.Subject == "" {
+		return nil, nil
+	}
+
+	return &User{
+		Name:   claims.Subject,
+		Groups: claims.Groups,
+	}, nil
+}
+
+func (j *jwtAuthenticator) verify(algorithm, signed string, signature []byte) error {
+	switch {
+	case algorithm == "HS256" && j.secret != nil:
+		mac := hmac.New(sha256.New, j.secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case algorithm == "RS256" && j.publicKey != nil:
+		hash := sha256.Sum256([]byte(signed))
+		return rsa.VerifyPKCS1v15(j.publicKey, crypto.SHA256, hash[:], signature)
+	default:
+		return fmt.Errorf("unsupported algorithm %s", algorithm)
+	}
+}
+
+func decodeSegment(segment string, obj interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, obj)
+}
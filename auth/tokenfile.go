@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type staticToken struct {
+	token string
+	user  *User
+}
+
+type tokenFileAuthenticator struct {
+	tokens []staticToken
+}
+
+// NewTokenFileAuthenticator reads tokens from a CSV file in the same format as the
+// apiserver --token-auth-file: token,user,uid,"group1,group2"
+func NewTokenFileAuthenticator(path string) (Authenticator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	t := &tokenFileAuthenticator{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(record) < 3 || record[0] == "" || record[1] == "" {
+			return nil, fmt.Errorf("%s:%d: expected token,user,uid[,groups]", path, line)
+		}
+
+		user := &User{
+			Name: record[1],
+		}
+		if len(record) > 3 && record[3] != "" {
+			for _, group := range strings.Split(record[3], ",") {
+				user.Groups = append(user.Groups, strings.TrimSpace(group))
+			}
+		}
+
+		t.tokens = append(t.tokens, staticToken{
+			token: record[0],
+			user:  user,
+		})
+	}
+
+	return t, nil
+}
+
+func (t *tokenFileAuthenticator) Authenticate(token string) (*User, error) {
+	for _, static := range t.tokens {
+		if subtle.ConstantTimeCompare([]byte(static.token), []byte(token)) == 1 {
+			return static.user, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"time"
+
+	"github.com/hashicorp/golang-lru"
+	authv1 "k8s.io/api/authentication/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	tokenReviewCacheSize = 1024
+	tokenReviewCacheTTL  = 10 * time.Second
+)
+
+type cachedReview struct {
+	user    *User
+	expires time.Time
+}
+
+type tokenReviewAuthenticator struct {
+	k8sClient kubernetes.Interface
+	cache     *lru.Cache
+}
+
+// NewTokenReviewAuthenticator asks the apiserver to verify tokens. Answers are
+// cached for a short time so a busy client doesn't cause a review per request.
+func NewTokenReviewAuthenticator(k8sClient kubernetes.Interface) (Authenticator, error) {
+	cache, err := lru.New(tokenReviewCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenReviewAuthenticator{
+		k8sClient: k8sClient,
+		cache:     cache,
+	}, nil
+}
+
+func (t *tokenReviewAuthenticator) Authenticate(token string) (*User, error) {
+	key := sha256.Sum256([]byte(token))
+	if cached, ok := t.cache.Get(key); ok {
+		review := cached.(cachedReview)
+		if time.Now().Before(review.expires) {
+			return review.user, nil
+		}
+		t.cache.Remove(key)
+	}
+
+	review, err := t.k8sClient.AuthenticationV1().TokenReviews().Create(&authv1.TokenReview{
+		Spec: authv1.TokenReviewSpec{
+			Token: token,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var user *User
+	if review.Status.Authenticated {
+		user = &User{
+			Name:   review.Status.User.Username,
+			Groups: review.Status.User.Groups,
+		}
+	}
+
+	t.cache.Add(key, cachedReview{
+		user:    user,
+		expires: time.Now().Add(tokenReviewCacheTTL),
+	})
+
+	return user, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// certLoader serves a certificate pair from disk and loads it again when either
+// file changes, so renewed certificates are picked up without a restart
+type certLoader struct {
+	sync.Mutex
+	certFile, keyFile string
+	certTime, keyTime time.Time
+	cert              *tls.Certificate
+}
+
+func newCertLoader(certFile, keyFile string) (*certLoader, error) {
+	c := &certLoader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	return c, c.reload()
+}
+
+func (c *certLoader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.reload(); err != nil {
+		// Keep serving the last good pair, the files may be halfway through being replaced
+		logrus.Errorf("Failed to reload certificate %s: %v", c.certFile, err)
+	}
+
+	return c.cert, nil
+}
+
+func (c *certLoader) reload() error {
+	certInfo, err := os.Stat(c.certFile)
+	if err != nil {
+		return err
+	}
+	keyInfo, err := os.Stat(c.keyFile)
+	if err != nil {
+		return err
+	}
+
+	if c.cert != nil && certInfo.ModTime().Equal(c.certTime) && keyInfo.ModTime().Equal(c.keyTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+
+	if c.cert != nil {
+		logrus.Infof("Reloaded certificate %s", c.certFile)
+	}
+
+	c.cert = &cert
+	c.certTime = certInfo.ModTime()
+	c.keyTime = keyInfo.ModTime()
+	return nil
+}
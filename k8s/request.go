@@ -0,0 +1,67 @@
+package k8s
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+)
+
+// CoreV1 is the path prefix of core/v1 resources
+var CoreV1 = []string{"api", "v1"}
+
+// ImpersonationHeaders hold the identity of the caller, requests sent with them
+// are checked by the apiserver against the caller's permissions
+var ImpersonationHeaders = []string{
+	"Impersonate-User",
+	"Impersonate-Group",
+}
+
+// Identity copies the impersonation headers, for requests made after the
+// request of the caller is done
+func Identity(header http.Header) http.Header {
+	result := http.Header{}
+	for _, name := range ImpersonationHeaders {
+		name = http.CanonicalHeaderKey(name)
+		result[name] = header[name]
+	}
+	return result
+}
+
+// Request starts a request made with the identity in header, namespace and name
+// are left out when empty
+func Request(ctx context.Context, k8sClient rest.Interface, header http.Header, method string, prefix []string, namespace, resource, name string) *rest.Request {
+	req := k8sClient.Verb(method).
+		Prefix(prefix...).
+		Resource(resource).
+		Context(ctx)
+	if namespace != "" {
+		req.Namespace(namespace)
+	}
+	if name != "" {
+		req.Name(name)
+	}
+
+	for _, name := range ImpersonationHeaders {
+		req.SetHeader(name, header[http.CanonicalHeaderKey(name)]...)
+	}
+
+	return req
+}
+
+// CallerRequest starts a request made with the identity of the caller of apiContext
+func CallerRequest(apiContext *types.APIContext, k8sClient rest.Interface, method string, prefix []string, namespace, resource, name string) *rest.Request {
+	return Request(apiContext.Request.Context(), k8sClient, apiContext.Request.Header, method, prefix, namespace, resource, name)
+}
+
+// TranslateError turns apiserver errors into API errors with the same status
+func TranslateError(err error) error {
+	if apiError, ok := err.(errors.APIStatus); ok {
+		status := apiError.Status()
+		return httperror.NewAPIErrorLong(int(status.Code), string(status.Reason), status.Message)
+	}
+	return err
+}
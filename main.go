@@ -2,38 +2,190 @@ package main
 
 import (
 	"context"
-	"os"
-
-	"fmt"
+	"crypto/tls"
+	"errors"
+	"flag"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/rancher/cluster-api/api/subscribe"
+	"github.com/rancher/cluster-api/auth"
 	"github.com/rancher/cluster-api/server"
 	"github.com/rancher/types/config"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
+var (
+	VERSION = "dev"
+
+	listenAddress = flag.String("listen", envDefault("LISTEN_ADDRESS", "0.0.0.0:1234"), "Address to listen on [$LISTEN_ADDRESS]")
+	tlsCertFile   = flag.String("tls-cert", os.Getenv("TLS_CERT_FILE"), "Serve HTTPS with this certificate, reloaded when the file changes [$TLS_CERT_FILE]")
+	tlsKeyFile    = flag.String("tls-key", os.Getenv("TLS_KEY_FILE"), "Private key for --tls-cert [$TLS_KEY_FILE]")
+	clusterName   = flag.String("cluster-name", envDefault("CLUSTER_NAME", "local"), "Name of the cluster being served [$CLUSTER_NAME]")
+	kubeConfig    = flag.String("kubeconfig", os.Getenv("KUBECONFIG"), "Kube config to use, the in-cluster config is used if empty [$KUBECONFIG]")
+	tokenReview   = flag.Bool("token-review", true, "Verify bearer tokens with a TokenReview against the apiserver")
+	tokenFile     = flag.String("token-auth-file", os.Getenv("TOKEN_AUTH_FILE"), "Accept the tokens in this CSV file, token,user,uid,\"group1,group2\" [$TOKEN_AUTH_FILE]")
+	jwtKeyFile    = flag.String("jwt-key-file", os.Getenv("JWT_KEY_FILE"), "Accept JWTs signed with the HS256 secret or RSA public key in this file [$JWT_KEY_FILE]")
+	jwtIssuer     = flag.String("jwt-issuer", os.Getenv("JWT_ISSUER"), "Only accept JWTs from this issuer [$JWT_ISSUER]")
+	drainTimeout  = flag.Duration("drain-timeout", 30*time.Second, "How long to wait for open requests and subscriptions on shutdown")
+)
+
+var (
+	errMissingTLSPair  = errors.New("--tls-cert and --tls-key must be set together")
+	errNoAuthenticator = errors.New("no authentication configured, enable --token-review or set --token-auth-file or --jwt-key-file")
+)
+
 func main() {
+	flag.Parse()
+
 	if err := run(); err != nil {
-		panic(err)
+		logrus.Fatal(err)
+	}
+}
+
+func envDefault(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+func restConfig() (*rest.Config, error) {
+	if *kubeConfig == "" {
+		return rest.InClusterConfig()
+	}
+	return clientcmd.BuildConfigFromFlags("", *kubeConfig)
+}
+
+func authenticators(app *config.ClusterContext) (auth.Authenticator, error) {
+	var result auth.Authenticators
+
+	if *tokenFile != "" {
+		authenticator, err := auth.NewTokenFileAuthenticator(*tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, authenticator)
+	}
+
+	if *jwtKeyFile != "" {
+		authenticator, err := auth.NewJWTAuthenticator(*jwtKeyFile, *jwtIssuer)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, authenticator)
 	}
+
+	if *tokenReview {
+		authenticator, err := auth.NewTokenReviewAuthenticator(app.K8sClient)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, authenticator)
+	}
+
+	if len(result) == 0 {
+		return nil, errNoAuthenticator
+	}
+
+	return result, nil
 }
 
 func run() error {
-	kubeConfig, err := clientcmd.BuildConfigFromFlags("", os.Getenv("KUBECONFIG"))
+	if (*tlsCertFile == "") != (*tlsKeyFile == "") {
+		return errMissingTLSPair
+	}
+
+	kubeConfig, err := restConfig()
 	if err != nil {
 		return err
 	}
 
-	app, err := config.NewClusterContext(*kubeConfig, *kubeConfig, "local")
+	app, err := config.NewClusterContext(*kubeConfig, *kubeConfig, *clusterName)
 	if err != nil {
 		return err
 	}
 
-	handler, err := server.New(context.Background(), app)
+	authenticator, err := authenticators(app)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println("Listening on 0.0.0.0:1234")
-	return http.ListenAndServe("0.0.0.0:1234", handler)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler, err := server.New(ctx, app, VERSION, authenticator)
+	if err != nil {
+		return err
+	}
+
+	httpServer := &http.Server{
+		Addr:    *listenAddress,
+		Handler: handler,
+	}
+	// Subscriptions would keep Shutdown waiting, they are told to go away instead
+	httpServer.RegisterOnShutdown(subscribe.Close)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serve(httpServer)
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serveErr:
+		return err
+	case sig := <-signals:
+		logrus.Infof("Received %s, shutting down", sig)
+	}
+
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), *drainTimeout)
+	defer drainCancel()
+
+	// Requests finish before anything they use is stopped
+	err = httpServer.Shutdown(drainCtx)
+
+	// Stops the watches, informers and node drains started by server.New
+	cancel()
+
+	// Websocket subscriptions are not waited for by Shutdown
+	if drainErr := subscribe.Drain(drainCtx); err == nil {
+		err = drainErr
+	}
+	return err
+}
+
+func serve(httpServer *http.Server) error {
+	var err error
+	if *tlsCertFile == "" {
+		logrus.Infof("Cluster API %s listening on %s", VERSION, *listenAddress)
+		err = httpServer.ListenAndServe()
+	} else {
+		err = serveTLS(httpServer)
+	}
+
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+func serveTLS(httpServer *http.Server) error {
+	certs, err := newCertLoader(*tlsCertFile, *tlsKeyFile)
+	if err != nil {
+		return err
+	}
+	httpServer.TLSConfig = &tls.Config{
+		GetCertificate: certs.GetCertificate,
+	}
+
+	logrus.Infof("Cluster API %s listening on %s with TLS", VERSION, *listenAddress)
+	return httpServer.ListenAndServeTLS("", "")
 }
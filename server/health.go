@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rancher/norman/types"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const readyTimeout = 5 * time.Second
+
+type readiness struct {
+	k8sClient    kubernetes.Interface
+	apiExtClient clientset.Interface
+	crdName      string
+}
+
+func newReadiness(k8sClient kubernetes.Interface, apiExtClient clientset.Interface, crdSchema *types.Schema) *readiness {
+	return &readiness{
+		k8sClient:    k8sClient,
+		apiExtClient: apiExtClient,
+		// Same name the CRD store registers the schema under
+		crdName: strings.ToLower(crdSchema.PluralName + "." + crdSchema.Version.Group),
+	}
+}
+
+func (r *readiness) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if err := r.check(req); err != nil {
+		http.Error(rw, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	rw.Write([]byte("ok"))
+}
+
+func (r *readiness) check(req *http.Request) error {
+	err := r.k8sClient.Discovery().RESTClient().Get().
+		AbsPath("/healthz").
+		Context(req.Context()).
+		Timeout(readyTimeout).
+		Do().
+		Error()
+	if err != nil {
+		return fmt.Errorf("apiserver is not reachable: %v", err)
+	}
+
+	crd, err := r.apiExtClient.ApiextensionsV1beta1().CustomResourceDefinitions().Get(r.crdName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get CRD %s: %v", r.crdName, err)
+	}
+
+	for _, condition := range crd.Status.Conditions {
+		if condition.Type == apiext.Established && condition.Status == apiext.ConditionTrue {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("CRD %s is not established", r.crdName)
+}
+
+func healthz(rw http.ResponseWriter, req *http.Request) {
+	rw.Write([]byte("ok"))
+}
+
+func versionHandler(version string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(map[string]string{
+			"version": version,
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/rancher/cluster-api/api/namespace"
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/norman/parse"
 	"github.com/rancher/norman/types"
@@ -15,7 +16,7 @@ import (
 var mgmtSchemas = types.NewSchemas().
 	AddSchemas(managementSchema.Schemas)
 
-func URLParser(clusterName string, schemas *types.Schemas, url *url.URL) (parse.ParsedURL, error) {
+func URLParser(clusterName string, projects *namespace.ProjectCache, schemas *types.Schemas, url *url.URL) (parse.ParsedURL, error) {
 	parsedURL, err := parse.DefaultURLParser(schemas, url)
 	if err != nil {
 		return parse.ParsedURL{}, err
@@ -32,12 +33,33 @@ func URLParser(clusterName string, schemas *types.Schemas, url *url.URL) (parse.
 		return parse.ParsedURL{}, httperror.NewAPIError(httperror.NotFound, "failed to parse location")
 	}
 
+	projectID := parsedURL.ID
 	parsedURL.SubContextPrefix = "/" + parsedURL.ID
 	parsedURL.Type, parsedURL.ID, parsedURL.Link = threeSplit(parsedURL.Link)
 
+	// /v3/projects/<project>/namespaces/<namespace>/<type>/<id>/<link>
+	if parsedURL.Version == projectSchema.Version.Path && isNamespaceSubContext(schemas, parsedURL) {
+		if !projects.InProject(parsedURL.ID, projectID) {
+			return parse.ParsedURL{}, httperror.NewAPIError(httperror.NotFound, "failed to find namespace "+parsedURL.ID)
+		}
+
+		parsedURL.SubContext["namespaces"] = parsedURL.ID
+		parsedURL.SubContextPrefix += "/namespaces/" + parsedURL.ID
+		parsedURL.Type, parsedURL.ID, parsedURL.Link = threeSplit(parsedURL.Link)
+	}
+
 	return parsedURL, nil
 }
 
+func isNamespaceSubContext(schemas *types.Schemas, parsedURL parse.ParsedURL) bool {
+	if parsedURL.Type != "namespaces" || parsedURL.ID == "" || parsedURL.Link == "" {
+		return false
+	}
+
+	subType, _, _ := threeSplit(parsedURL.Link)
+	return schemas.Schema(&projectSchema.Version, subType) != nil
+}
+
 func threeSplit(link string) (string, string, string) {
 	parts := strings.SplitN(link, "/", 3)
 
@@ -5,30 +5,59 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/rancher/cluster-api/api/namespace"
 	"github.com/rancher/cluster-api/api/setup"
+	"github.com/rancher/cluster-api/auth"
 	"github.com/rancher/cluster-api/store"
 	"github.com/rancher/norman-rbac"
 	normanapi "github.com/rancher/norman/api"
 	"github.com/rancher/norman/parse"
 	"github.com/rancher/norman/types"
+	projectSchema "github.com/rancher/types/apis/project.cattle.io/v3/schema"
+	"github.com/rancher/types/client/project/v3"
 	"github.com/rancher/types/config"
+	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 )
 
-func New(ctx context.Context, cluster *config.ClusterContext) (http.Handler, error) {
+func New(ctx context.Context, cluster *config.ClusterContext, version string, authenticator auth.Authenticator) (http.Handler, error) {
 	if err := setup.Schemas(ctx, cluster, cluster.Schemas); err != nil {
 		return nil, err
 	}
 
 	server := normanapi.NewAPIServer()
-	server.AccessControl = rbac.NewAccessControl(cluster.RBAC)
+	accessControl, err := auth.NewAccessControl(rbac.NewAccessControl(cluster.RBAC), cluster.K8sClient, setup.Resources)
+	if err != nil {
+		return nil, err
+	}
+
+	server.AccessControl = accessControl
+	projects := namespace.NewProjectCache(cluster.Core)
 	server.URLParser = func(schemas *types.Schemas, url *url.URL) (parse.ParsedURL, error) {
-		return URLParser(cluster.ClusterName, schemas, url)
+		return URLParser(cluster.ClusterName, projects, schemas, url)
 	}
-	server.StoreWrapper = store.ProjectSetter(server.StoreWrapper)
+	server.StoreWrapper = store.ProjectSetter(store.CreateChecker(store.ProjectScoper(server.StoreWrapper, projects), accessControl),
+		projects)
 
 	if err := server.AddSchemas(cluster.Schemas); err != nil {
 		return nil, err
 	}
 
-	return server, nil
+	// Fills the namespace and RBAC caches used above
+	if err := cluster.Start(ctx); err != nil {
+		return nil, err
+	}
+
+	apiExtClient, err := clientset.NewForConfig(&cluster.RESTConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", healthz)
+	mux.Handle("/readyz", newReadiness(cluster.K8sClient, apiExtClient,
+		cluster.Schemas.Schema(&projectSchema.Version, client.WorkloadType)))
+	mux.Handle("/version", versionHandler(version))
+	mux.Handle("/", auth.NewHandler(authenticator, server))
+
+	return mux, nil
 }
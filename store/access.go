@@ -0,0 +1,31 @@
+package store
+
+import (
+	"github.com/rancher/cluster-api/auth"
+	"github.com/rancher/norman/api"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+)
+
+// CreateChecker checks creates against the namespace in the body, which isn't
+// known yet when the access control is first asked
+func CreateChecker(wrapper api.StoreWrapper, accessControl *auth.AccessControl) api.StoreWrapper {
+	return func(store types.Store) types.Store {
+		return wrapper(&createCheckerStore{
+			Store:         store,
+			accessControl: accessControl,
+		})
+	}
+}
+
+type createCheckerStore struct {
+	types.Store
+	accessControl *auth.AccessControl
+}
+
+func (c *createCheckerStore) Create(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}) (map[string]interface{}, error) {
+	if !c.accessControl.CheckCreate(apiContext, schema, data) {
+		return nil, httperror.NewAPIError(httperror.PermissionDenied, "Can not create "+schema.ID)
+	}
+	return c.Store.Create(apiContext, schema, data)
+}
@@ -1,6 +1,7 @@
 package ingress
 
 import (
+	"github.com/rancher/cluster-api/store/selector"
 	"github.com/rancher/norman/store/proxy"
 	"github.com/rancher/norman/types"
 	"github.com/rancher/types/config"
@@ -16,12 +17,13 @@ type Store struct {
 
 func NewStore(workload *config.WorkloadContext) *Store {
 	return &Store{
-		Store: proxy.NewProxyStore(workload.UnversionedClient,
+		Store: selector.NewProxyStore(workload.UnversionedClient,
 			[]string{"apis"},
 			"extensions",
 			"v1beta1",
 			"Ingress",
-			"ingresses"),
+			"ingresses",
+			nil),
 		proxyStore: proxy.NewRawProxyStore(workload.UnversionedClient,
 			[]string{"apis"},
 			"extensions",
@@ -0,0 +1,327 @@
+package store
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rancher/cluster-api/api/namespace"
+	"github.com/rancher/cluster-api/auth"
+	"github.com/rancher/cluster-api/store/selector"
+	"github.com/rancher/norman/api"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/types/client/project/v3"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxNamespaceRequests is how many namespaces of a project are requested at once
+const maxNamespaceRequests = 8
+
+// ProjectScoper limits requests under /v3/projects/<id> to the namespaces of that
+// project. Lists and watches are made per namespace, so they only need the
+// permissions a project member has.
+func ProjectScoper(wrapper api.StoreWrapper, projects *namespace.ProjectCache) api.StoreWrapper {
+	return func(store types.Store) types.Store {
+		return wrapper(&projectScopedStore{
+			Store:    store,
+			projects: projects,
+		})
+	}
+}
+
+type projectScopedStore struct {
+	types.Store
+	projects *namespace.ProjectCache
+}
+
+// projectID returns the project the request is scoped to, if the schema is
+// namespaced and the request isn't already for a single namespace
+func (p *projectScopedStore) projectID(apiContext *types.APIContext, schema *types.Schema) string {
+	if _, ok := schema.ResourceFields[client.PodFieldNamespaceId]; !ok {
+		return ""
+	}
+	if _, ok := apiContext.SubContext["namespaces"]; ok {
+		return ""
+	}
+	return convert.ToString(apiContext.SubContext["projects"])
+}
+
+func (p *projectScopedStore) inProject(apiContext *types.APIContext, schema *types.Schema, id string) bool {
+	if _, ok := schema.ResourceFields[client.PodFieldNamespaceId]; !ok {
+		return true
+	}
+
+	_, ns, _ := auth.ParseID(id)
+	if subContext, ok := apiContext.SubContext["namespaces"]; ok {
+		return ns != "" && ns == subContext
+	}
+
+	projectID := p.projectID(apiContext, schema)
+	if projectID == "" {
+		return true
+	}
+
+	return ns != "" && p.projects.InProject(ns, projectID)
+}
+
+func (p *projectScopedStore) ByID(apiContext *types.APIContext, schema *types.Schema, id string) (map[string]interface{}, error) {
+	if !p.inProject(apiContext, schema, id) {
+		return nil, httperror.NewAPIError(httperror.NotFound, "failed to find "+id)
+	}
+	return p.Store.ByID(apiContext, schema, id)
+}
+
+func (p *projectScopedStore) Update(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}, id string) (map[string]interface{}, error) {
+	if !p.inProject(apiContext, schema, id) {
+		return nil, httperror.NewAPIError(httperror.NotFound, "failed to find "+id)
+	}
+	return p.Store.Update(apiContext, schema, data, id)
+}
+
+func (p *projectScopedStore) Delete(apiContext *types.APIContext, schema *types.Schema, id string) (map[string]interface{}, error) {
+	if !p.inProject(apiContext, schema, id) {
+		return nil, httperror.NewAPIError(httperror.NotFound, "failed to find "+id)
+	}
+	return p.Store.Delete(apiContext, schema, id)
+}
+
+func (p *projectScopedStore) Create(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}) (map[string]interface{}, error) {
+	if _, ok := schema.ResourceFields[client.PodFieldNamespaceId]; ok {
+		// Under /namespaces/<namespace> the namespace comes from the URL
+		if ns, ok := apiContext.SubContext["namespaces"]; ok {
+			if data == nil {
+				data = map[string]interface{}{}
+			}
+			if current, _ := data[client.PodFieldNamespaceId].(string); current == "" {
+				data[client.PodFieldNamespaceId] = ns
+			} else if current != ns {
+				return nil, httperror.NewFieldAPIError(httperror.InvalidOption, client.PodFieldNamespaceId, "must be "+ns)
+			}
+		}
+	}
+
+	projectID := p.projectID(apiContext, schema)
+	if projectID != "" {
+		ns, _ := data[client.PodFieldNamespaceId].(string)
+		if !p.projects.InProject(ns, projectID) {
+			return nil, httperror.NewFieldAPIError(httperror.InvalidReference, client.PodFieldNamespaceId, "namespace is not in project "+projectID)
+		}
+	}
+	return p.Store.Create(apiContext, schema, data)
+}
+
+// namespaces returns the namespaces to query, a namespaceId filter in the request
+// narrows it down to that namespace
+func (p *projectScopedStore) namespaces(projectID string, opt *types.QueryOptions) []string {
+	for _, condition := range opt.Conditions {
+		if condition.Field == client.PodFieldNamespaceId && condition.Value != "" {
+			if p.projects.InProject(condition.Value, projectID) {
+				return []string{condition.Value}
+			}
+			return nil
+		}
+	}
+	return p.projects.Namespaces(projectID)
+}
+
+func namespacedOptions(opt *types.QueryOptions, ns string) *types.QueryOptions {
+	nsOpt := *opt
+	nsOpt.Conditions = append([]*types.QueryCondition{
+		types.NewConditionFromString(client.PodFieldNamespaceId, types.ModifierEQ, ns),
+	}, opt.Conditions...)
+	return &nsOpt
+}
+
+// forEachNamespace calls f for every namespace, at most maxNamespaceRequests at
+// a time, and returns the first error
+func forEachNamespace(namespaces []string, f func(ns string) error) error {
+	running := make(chan struct{}, maxNamespaceRequests)
+	g := errgroup.Group{}
+	for _, ns := range namespaces {
+		ns := ns
+		running <- struct{}{}
+		g.Go(func() error {
+			defer func() { <-running }()
+			return f(ns)
+		})
+	}
+	return g.Wait()
+}
+
+func (p *projectScopedStore) List(apiContext *types.APIContext, schema *types.Schema, opt *types.QueryOptions) ([]map[string]interface{}, error) {
+	projectID := p.projectID(apiContext, schema)
+	if projectID == "" {
+		return p.Store.List(apiContext, schema, opt)
+	}
+
+	var (
+		lock   sync.Mutex
+		result []map[string]interface{}
+	)
+
+	err := forEachNamespace(p.namespaces(projectID, opt), func(ns string) error {
+		data, err := p.Store.List(apiContext, schema, namespacedOptions(opt, ns))
+		if err != nil {
+			return err
+		}
+
+		lock.Lock()
+		result = append(result, data...)
+		lock.Unlock()
+		return nil
+	})
+
+	return result, err
+}
+
+// Watch watches each namespace of the project, as project members may not be
+// allowed to watch the others. Namespaces that are added to the project are
+// watched from then on, and those that leave it are no longer watched.
+func (p *projectScopedStore) Watch(apiContext *types.APIContext, schema *types.Schema, opt *types.QueryOptions) (chan map[string]interface{}, error) {
+	projectID := p.projectID(apiContext, schema)
+	if projectID == "" {
+		return p.Store.Watch(apiContext, schema, opt)
+	}
+
+	ctx, cancel := context.WithCancel(apiContext.Request.Context())
+	w := &projectWatch{
+		store:      p,
+		apiContext: apiContext,
+		schema:     schema,
+		opt:        opt,
+		projectID:  projectID,
+		ctx:        ctx,
+		cancel:     cancel,
+		watches:    map[string]context.CancelFunc{},
+		result:     make(chan map[string]interface{}),
+	}
+
+	// Subscribed first, so a namespace added while the watches start isn't missed
+	changes := p.projects.Changes(ctx)
+	if err := w.sync(opt); err != nil {
+		// The watches that did start drain their streams once they see ctx is done
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		w.run(changes)
+		w.wg.Wait()
+		close(w.result)
+	}()
+
+	return w.result, nil
+}
+
+type projectWatch struct {
+	store      *projectScopedStore
+	apiContext *types.APIContext
+	schema     *types.Schema
+	opt        *types.QueryOptions
+	projectID  string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	result chan map[string]interface{}
+
+	// watches stops the watch of each namespace, only sync changes it
+	watches map[string]context.CancelFunc
+}
+
+// run keeps the watched namespaces in sync with the project until the watch ends
+func (w *projectWatch) run(changes <-chan struct{}) {
+	defer w.cancel()
+
+	// Namespaces that join later are watched from their current state, the
+	// resourceVersion the watch was resumed from says nothing about them
+	opt := *w.opt
+	opt.Options = map[string]string{}
+	for k, v := range w.opt.Options {
+		if k != selector.ResourceVersionOption {
+			opt.Options[k] = v
+		}
+	}
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-changes:
+			if err := w.sync(&opt); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// sync starts watching the namespaces that are in the project and stops watching
+// those that aren't anymore
+func (w *projectWatch) sync(opt *types.QueryOptions) error {
+	wanted := map[string]bool{}
+	for _, ns := range w.store.namespaces(w.projectID, w.opt) {
+		wanted[ns] = true
+	}
+
+	var added []string
+	for ns := range wanted {
+		if _, ok := w.watches[ns]; !ok {
+			added = append(added, ns)
+		}
+	}
+	for ns, stop := range w.watches {
+		if !wanted[ns] {
+			stop()
+			delete(w.watches, ns)
+		}
+	}
+
+	var lock sync.Mutex
+	return forEachNamespace(added, func(ns string) error {
+		ctx, stop := context.WithCancel(w.ctx)
+		watchContext := *w.apiContext
+		watchContext.Request = w.apiContext.Request.WithContext(ctx)
+
+		c, err := w.store.Store.Watch(&watchContext, w.schema, namespacedOptions(opt, ns))
+		if err != nil {
+			stop()
+			return err
+		}
+
+		lock.Lock()
+		w.watches[ns] = stop
+		lock.Unlock()
+		if c != nil {
+			w.read(ctx, c)
+		}
+		return nil
+	})
+}
+
+// read copies the stream of one namespace to the result until the namespace is
+// no longer watched. Once a stream ends on its own the project's watch is
+// incomplete, so it ends as a whole.
+func (w *projectWatch) read(ctx context.Context, c chan map[string]interface{}) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		for item := range c {
+			// Drained until the store closes it
+			if ctx.Err() != nil {
+				continue
+			}
+			// The namespace may have moved to another project before the watch stopped
+			if ns, _ := item[client.PodFieldNamespaceId].(string); !w.store.projects.InProject(ns, w.projectID) {
+				continue
+			}
+			select {
+			case w.result <- item:
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() == nil {
+			w.cancel()
+		}
+	}()
+}
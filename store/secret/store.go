@@ -3,7 +3,7 @@ package secret
 import (
 	"strings"
 
-	"github.com/rancher/norman/store/proxy"
+	"github.com/rancher/cluster-api/store/selector"
 	"github.com/rancher/norman/store/transform"
 	"github.com/rancher/norman/types"
 	"github.com/rancher/norman/types/convert"
@@ -25,12 +25,13 @@ func (s *Store) Create(apiContext *types.APIContext, schema *types.Schema, data
 func NewSecretStore(k8sClient rest.Interface, schemas *types.Schemas) *Store {
 	return &Store{
 		Store: &transform.Store{
-			Store: proxy.NewProxyStore(k8sClient,
+			Store: selector.NewProxyStore(k8sClient,
 				[]string{"api"},
 				"",
 				"v1",
 				"Secret",
-				"secrets"),
+				"secrets",
+				nil),
 			Transformer: func(apiContext *types.APIContext, data map[string]interface{}) (map[string]interface{}, error) {
 				if data == nil {
 					return data, nil
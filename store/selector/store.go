@@ -0,0 +1,322 @@
+package selector
+
+import (
+	"context"
+	ejson "encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rancher/cluster-api/k8s"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/store/proxy"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/apimachinery/pkg/runtime/serializer/streaming"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	restclientwatch "k8s.io/client-go/rest/watch"
+)
+
+const (
+	// LabelSelectorParam is the query parameter holding a label selector for lists and watches.
+	LabelSelectorParam = "labelSelector"
+	// ResourceVersionOption is the QueryOptions option a watch starts from.
+	ResourceVersionOption = "resourceVersion"
+	// ResourceVersionField holds the resourceVersion of listed and watched objects,
+	// which the metadata mapper drops. It is not part of any schema, so it is
+	// never written out.
+	ResourceVersionField = ".resourceVersion"
+)
+
+type watchErrorHandlerKey struct{}
+
+type listVersionHandlerKey struct{}
+
+// WithWatchErrorHandler returns a context whose watches pass the errors the
+// apiserver sends in the stream, such as 410 Gone for an expired resourceVersion,
+// to f. The watch ends after an error either way.
+func WithWatchErrorHandler(ctx context.Context, f func(error)) context.Context {
+	return context.WithValue(ctx, watchErrorHandlerKey{}, f)
+}
+
+// WithListVersionHandler returns a context whose lists pass the resourceVersion
+// of the list to f, which is where a watch of the same list picks up. Lists of
+// several stores call f once for each, possibly at the same time.
+func WithListVersionHandler(ctx context.Context, f func(string)) context.Context {
+	return context.WithValue(ctx, listVersionHandlerKey{}, f)
+}
+
+// commonFields are the field selectors every resource supports, keyed by API field.
+var commonFields = map[string]string{
+	"name": "metadata.name",
+}
+
+// Store is a proxy store that does lists and watches itself, so label and field
+// selectors are sent to the apiserver instead of fetching everything and
+// filtering in memory, and watches can start from a resourceVersion.
+type Store struct {
+	types.Store
+	k8sClient      rest.Interface
+	prefix         []string
+	group          string
+	version        string
+	resourcePlural string
+	authContext    map[string]string
+	fields         map[string]string
+}
+
+// NewProxyStore takes the arguments of proxy.NewProxyStore plus the field
+// selectors the resource supports beyond metadata.name, keyed by API field.
+func NewProxyStore(k8sClient rest.Interface,
+	prefix []string, group, version, kind, resourcePlural string, fields map[string]string) *Store {
+	return WrapStore(proxy.NewProxyStore(k8sClient,
+		prefix,
+		group,
+		version,
+		kind,
+		resourcePlural), k8sClient, prefix, group, version, resourcePlural, fields)
+}
+
+// WrapStore does the lists and watches of store, a proxy store for the resource
+// such as the one of a CRD store, and leaves the rest to it
+func WrapStore(store types.Store, k8sClient rest.Interface,
+	prefix []string, group, version, resourcePlural string, fields map[string]string) *Store {
+	allFields := map[string]string{}
+	for k, v := range commonFields {
+		allFields[k] = v
+	}
+	for k, v := range fields {
+		allFields[k] = v
+	}
+
+	return &Store{
+		Store:          store,
+		k8sClient:      k8sClient,
+		prefix:         prefix,
+		group:          group,
+		version:        version,
+		resourcePlural: resourcePlural,
+		authContext: map[string]string{
+			"apiGroup": group,
+			"resource": resourcePlural,
+		},
+		fields: allFields,
+	}
+}
+
+func (s *Store) List(apiContext *types.APIContext, schema *types.Schema, opt *types.QueryOptions) ([]map[string]interface{}, error) {
+	listOpts, err := s.listOptions(apiContext, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	req := s.common(getNamespace(apiContext, opt), s.k8sClient.Get()).
+		Context(apiContext.Request.Context()).
+		VersionedParams(listOpts, dynamic.VersionedParameterEncoderWithV1Fallback)
+
+	resultList := &unstructured.UnstructuredList{}
+	if err := req.Do().Into(resultList); err != nil {
+		return nil, k8s.TranslateError(err)
+	}
+
+	if onVersion, ok := apiContext.Request.Context().Value(listVersionHandlerKey{}).(func(string)); ok {
+		onVersion(resultList.GetResourceVersion())
+	}
+
+	var result []map[string]interface{}
+	for _, obj := range resultList.Items {
+		version := obj.GetResourceVersion()
+		data := fromInternal(schema, obj.Object)
+		data[ResourceVersionField] = version
+		result = append(result, data)
+	}
+
+	return apiContext.AccessControl.FilterList(apiContext, result, s.authContext), nil
+}
+
+func (s *Store) Watch(apiContext *types.APIContext, schema *types.Schema, opt *types.QueryOptions) (chan map[string]interface{}, error) {
+	listOpts, err := s.listOptions(apiContext, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	listOpts.Watch = true
+	if opt != nil {
+		listOpts.ResourceVersion = opt.Options[ResourceVersionOption]
+	}
+	req := s.common(getNamespace(apiContext, opt), s.k8sClient.Get()).
+		VersionedParams(listOpts, dynamic.VersionedParameterEncoderWithV1Fallback)
+
+	body, err := req.Stream()
+	if err != nil {
+		return nil, k8s.TranslateError(err)
+	}
+
+	framer := json.Framer.NewFrameReader(body)
+	decoder := streaming.NewDecoder(framer, &unstructuredDecoder{})
+	watcher := watch.NewStreamWatcher(restclientwatch.NewDecoder(decoder, &unstructuredDecoder{}))
+
+	go func() {
+		<-apiContext.Request.Context().Done()
+		watcher.Stop()
+	}()
+
+	onError, _ := apiContext.Request.Context().Value(watchErrorHandlerKey{}).(func(error))
+
+	result := make(chan map[string]interface{})
+	go func() {
+		defer close(result)
+		for event := range watcher.ResultChan() {
+			data, ok := event.Object.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			if event.Type == watch.Error {
+				watcher.Stop()
+				if onError != nil {
+					onError(statusError(data))
+				}
+				return
+			}
+			version := data.GetResourceVersion()
+			fromInternal(schema, data.Object)
+			data.Object[ResourceVersionField] = version
+			if event.Type == watch.Deleted && data.Object != nil {
+				data.Object[".removed"] = true
+			}
+			result <- apiContext.AccessControl.Filter(apiContext, data.Object, s.authContext)
+		}
+	}()
+
+	return result, nil
+}
+
+// LabelSelector returns the label selector of the request, or an InvalidFormat
+// error if it doesn't parse
+func LabelSelector(apiContext *types.APIContext) (string, error) {
+	if apiContext.Query == nil {
+		return "", nil
+	}
+
+	labelSelector := strings.TrimSpace(apiContext.Query.Get(LabelSelectorParam))
+	if labelSelector == "" {
+		return "", nil
+	}
+	if _, err := labels.Parse(labelSelector); err != nil {
+		return "", httperror.NewAPIError(httperror.InvalidFormat,
+			fmt.Sprintf("invalid %s: %v", LabelSelectorParam, err))
+	}
+	return labelSelector, nil
+}
+
+// listOptions returns the selectors for the request
+func (s *Store) listOptions(apiContext *types.APIContext, opt *types.QueryOptions) (*metav1.ListOptions, error) {
+	labelSelector, err := LabelSelector(apiContext)
+	if err != nil {
+		return nil, err
+	}
+
+	return &metav1.ListOptions{
+		LabelSelector: labelSelector,
+		FieldSelector: s.fieldSelector(opt),
+	}, nil
+}
+
+// fieldSelector translates the eq and ne conditions on supported fields. The
+// conditions are still applied afterwards, so anything left out here only costs
+// transfer, not correctness.
+func (s *Store) fieldSelector(opt *types.QueryOptions) string {
+	if opt == nil {
+		return ""
+	}
+
+	var selectors []string
+	for _, condition := range opt.Conditions {
+		field, ok := s.fields[condition.Field]
+		if !ok {
+			continue
+		}
+
+		cond := condition.ToCondition()
+		value := convert.ToString(cond.Value)
+		if value == "" || strings.ContainsAny(value, ",=!\\") {
+			continue
+		}
+
+		switch cond.Modifier {
+		case types.ModifierEQ:
+			selectors = append(selectors, field+"="+value)
+		case types.ModifierNE:
+			selectors = append(selectors, field+"!="+value)
+		}
+	}
+
+	return strings.Join(selectors, ",")
+}
+
+func (s *Store) common(namespace string, req *rest.Request) *rest.Request {
+	prefix := append([]string{}, s.prefix...)
+	if s.group != "" {
+		prefix = append(prefix, s.group)
+	}
+	prefix = append(prefix, s.version)
+	req.Prefix(prefix...).
+		Resource(s.resourcePlural)
+
+	if namespace != "" {
+		req.Namespace(namespace)
+	}
+
+	return req
+}
+
+// getNamespace matches the proxy store: the namespace sub-context wins over a
+// namespaceId condition, which only narrows the request when it is an eq.
+func getNamespace(apiContext *types.APIContext, opt *types.QueryOptions) string {
+	if val, ok := apiContext.SubContext["namespaces"]; ok {
+		return convert.ToString(val)
+	}
+
+	for _, condition := range opt.Conditions {
+		if condition.Field == "namespaceId" && condition.Value != "" && condition.ToCondition().Modifier == types.ModifierEQ {
+			return condition.Value
+		}
+	}
+
+	return ""
+}
+
+func fromInternal(schema *types.Schema, data map[string]interface{}) map[string]interface{} {
+	if schema.Mapper != nil {
+		schema.Mapper.FromInternal(data)
+	}
+
+	return data
+}
+
+// statusError turns the Status object of a watch error event into an API error
+func statusError(data *unstructured.Unstructured) error {
+	status := &metav1.Status{}
+	if err := convert.ToObj(data.Object, status); err != nil {
+		return err
+	}
+	return errors.FromObject(status)
+}
+
+type unstructuredDecoder struct {
+}
+
+func (d *unstructuredDecoder) Decode(data []byte, defaults *schema.GroupVersionKind, into runtime.Object) (runtime.Object, *schema.GroupVersionKind, error) {
+	if into == nil {
+		into = &unstructured.Unstructured{}
+	}
+	return into, defaults, ejson.Unmarshal(data, &into)
+}
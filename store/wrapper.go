@@ -1,26 +1,25 @@
 package store
 
 import (
-	"strings"
-
 	"github.com/rancher/cluster-api/api/namespace"
 	"github.com/rancher/norman/api"
 	"github.com/rancher/norman/types"
 	"github.com/rancher/norman/types/convert"
-	"github.com/rancher/norman/types/definition"
 	"github.com/rancher/types/client/project/v3"
 )
 
-func ProjectSetter(wrapper api.StoreWrapper) api.StoreWrapper {
+func ProjectSetter(wrapper api.StoreWrapper, projects *namespace.ProjectCache) api.StoreWrapper {
 	return func(store types.Store) types.Store {
 		return wrapper(&projectIDSetterStore{
-			Store: store,
+			Store:    store,
+			projects: projects,
 		})
 	}
 }
 
 type projectIDSetterStore struct {
 	types.Store
+	projects *namespace.ProjectCache
 }
 
 func (p *projectIDSetterStore) Create(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}) (map[string]interface{}, error) {
@@ -29,7 +28,7 @@ func (p *projectIDSetterStore) Create(apiContext *types.APIContext, schema *type
 		return nil, err
 	}
 
-	return lookupAndSetProjectID(apiContext, schema, data)
+	return p.lookupAndSetProjectID(apiContext, schema, data)
 }
 
 func (p *projectIDSetterStore) Delete(apiContext *types.APIContext, schema *types.Schema, id string) (map[string]interface{}, error) {
@@ -38,7 +37,7 @@ func (p *projectIDSetterStore) Delete(apiContext *types.APIContext, schema *type
 		return nil, err
 	}
 
-	return lookupAndSetProjectID(apiContext, schema, data)
+	return p.lookupAndSetProjectID(apiContext, schema, data)
 }
 
 func (p *projectIDSetterStore) ByID(apiContext *types.APIContext, schema *types.Schema, id string) (map[string]interface{}, error) {
@@ -47,7 +46,7 @@ func (p *projectIDSetterStore) ByID(apiContext *types.APIContext, schema *types.
 		return nil, err
 	}
 
-	return lookupAndSetProjectID(apiContext, schema, data)
+	return p.lookupAndSetProjectID(apiContext, schema, data)
 }
 
 func (p *projectIDSetterStore) Update(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}, id string) (map[string]interface{}, error) {
@@ -56,7 +55,7 @@ func (p *projectIDSetterStore) Update(apiContext *types.APIContext, schema *type
 		return nil, err
 	}
 
-	return lookupAndSetProjectID(apiContext, schema, data)
+	return p.lookupAndSetProjectID(apiContext, schema, data)
 }
 
 func (p *projectIDSetterStore) List(apiContext *types.APIContext, schema *types.Schema, opt *types.QueryOptions) ([]map[string]interface{}, error) {
@@ -69,13 +68,8 @@ func (p *projectIDSetterStore) List(apiContext *types.APIContext, schema *types.
 		return datas, nil
 	}
 
-	namespaceMap, err := namespace.ProjectMap(apiContext)
-	if err != nil {
-		return nil, err
-	}
-
 	for _, data := range datas {
-		setProjectID(namespaceMap, data)
+		p.setProjectID(apiContext, data)
 	}
 
 	return datas, nil
@@ -87,40 +81,23 @@ func (p *projectIDSetterStore) Watch(apiContext *types.APIContext, schema *types
 		return nil, err
 	}
 
-	namespaceMap, err := namespace.ProjectMap(apiContext)
-	if err != nil {
-		return nil, err
-	}
-
 	return convert.Chan(c, func(data map[string]interface{}) map[string]interface{} {
-		typeName := definition.GetType(data)
-		if strings.Contains(typeName, "namespace") || strings.Contains(typeName, "project") {
-			tempNamespaceMap, err := namespace.ProjectMap(apiContext)
-			if err == nil {
-				namespaceMap = tempNamespaceMap
-			}
-		}
-		setProjectID(namespaceMap, data)
+		p.setProjectID(apiContext, data)
 		return data
 	}), nil
 }
 
-func lookupAndSetProjectID(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}) (map[string]interface{}, error) {
+func (p *projectIDSetterStore) lookupAndSetProjectID(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}) (map[string]interface{}, error) {
 	if _, ok := schema.ResourceFields[client.NamespaceFieldProjectID]; !ok || schema.ID == client.NamespaceType {
 		return data, nil
 	}
 
-	namespaceMap, err := namespace.ProjectMap(apiContext)
-	if err != nil {
-		return nil, err
-	}
-
-	setProjectID(namespaceMap, data)
+	p.setProjectID(apiContext, data)
 
 	return data, nil
 }
 
-func setProjectID(namespaceMap map[string]string, data map[string]interface{}) {
+func (p *projectIDSetterStore) setProjectID(apiContext *types.APIContext, data map[string]interface{}) {
 	if data == nil {
 		return
 	}
@@ -131,5 +108,5 @@ func setProjectID(namespaceMap map[string]string, data map[string]interface{}) {
 		return
 	}
 
-	data[client.NamespaceFieldProjectID] = namespaceMap[namespace]
+	data[client.NamespaceFieldProjectID] = p.projects.ProjectID(apiContext, namespace)
 }